@@ -28,7 +28,12 @@ type CounterProcessor struct {
 	store          fssdk.Store
 	counterMap     map[string]int64
 	totalProcessed int64
-	keyPrefix      string
+	config         CounterConfig
+}
+
+// CounterConfig is bound from the init config.
+type CounterConfig struct {
+	KeyPrefix string `fs:"key_prefix"`
 }
 
 func (p *CounterProcessor) Init(ctx fssdk.Context, config map[string]string) error {
@@ -37,10 +42,14 @@ func (p *CounterProcessor) Init(ctx fssdk.Context, config map[string]string) err
 		return err
 	}
 
+	p.config = CounterConfig{}
+	if err := fssdk.BindConfig(config, &p.config); err != nil {
+		return err
+	}
+
 	p.store = store
 	p.counterMap = make(map[string]int64)
 	p.totalProcessed = 0
-	p.keyPrefix = strings.TrimSpace(config["key_prefix"])
 	return nil
 }
 
@@ -58,7 +67,7 @@ func (p *CounterProcessor) Process(ctx fssdk.Context, sourceID uint32, data []by
 
 	p.totalProcessed++
 
-	fullKey := p.keyPrefix + inputStr
+	fullKey := p.config.KeyPrefix + inputStr
 	existing, found, err := p.store.GetState([]byte(fullKey))
 	if err != nil {
 		return err
@@ -101,7 +110,7 @@ func (p *CounterProcessor) Close(ctx fssdk.Context) error {
 	p.store = nil
 	p.counterMap = nil
 	p.totalProcessed = 0
-	p.keyPrefix = ""
+	p.config = CounterConfig{}
 	return nil
 }
 
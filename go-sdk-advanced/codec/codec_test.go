@@ -0,0 +1,98 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec/codectest"
+)
+
+func less[T int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64 | string](a, b T) bool {
+	return a < b
+}
+
+func TestBuiltinCodecConformance(t *testing.T) {
+	t.Run("Bool", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.BoolCodec{},
+			func(r *rand.Rand) bool { return r.Intn(2) == 1 },
+			func(a, b bool) bool { return !a && b })
+	})
+	t.Run("Int", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.IntCodec{},
+			func(r *rand.Rand) int { return int(r.Uint64()) }, less[int])
+	})
+	t.Run("Int8", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Int8Codec{},
+			func(r *rand.Rand) int8 { return int8(r.Uint32()) }, less[int8])
+	})
+	t.Run("Int16", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Int16Codec{},
+			func(r *rand.Rand) int16 { return int16(r.Uint32()) }, less[int16])
+	})
+	t.Run("Int32", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Int32Codec{},
+			func(r *rand.Rand) int32 { return int32(r.Uint32()) }, less[int32])
+	})
+	t.Run("Int64", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Int64Codec{},
+			func(r *rand.Rand) int64 { return int64(r.Uint64()) }, less[int64])
+	})
+	t.Run("Uint", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.UintCodec{},
+			func(r *rand.Rand) uint { return uint(r.Uint64()) }, less[uint])
+	})
+	t.Run("Uint8", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Uint8Codec{},
+			func(r *rand.Rand) uint8 { return uint8(r.Uint32()) }, less[uint8])
+	})
+	t.Run("Uint16", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Uint16Codec{},
+			func(r *rand.Rand) uint16 { return uint16(r.Uint32()) }, less[uint16])
+	})
+	t.Run("Uint32", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Uint32Codec{},
+			func(r *rand.Rand) uint32 { return r.Uint32() }, less[uint32])
+	})
+	t.Run("Uint64", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Uint64Codec{},
+			func(r *rand.Rand) uint64 { return r.Uint64() }, less[uint64])
+	})
+	t.Run("Float32", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Float32Codec{},
+			func(r *rand.Rand) float32 { return math.Float32frombits(r.Uint32()) }, codectest.Float32Less)
+	})
+	t.Run("Float64", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.Float64Codec{},
+			func(r *rand.Rand) float64 { return math.Float64frombits(r.Uint64()) }, codectest.Float64Less)
+	})
+	t.Run("String", func(t *testing.T) {
+		codectest.RunCodecConformance(t, codec.StringCodec{},
+			func(r *rand.Rand) string {
+				b := make([]byte, r.Intn(16))
+				r.Read(b)
+				return string(b)
+			}, less[string])
+	})
+	t.Run("JSON", func(t *testing.T) {
+		type point struct {
+			X, Y int
+			Tag  string
+		}
+		codectest.RunCodecConformance(t, codec.JSONCodec[point]{},
+			func(r *rand.Rand) point { return point{X: r.Int(), Y: -r.Int(), Tag: "p"} }, nil)
+	})
+}
@@ -0,0 +1,260 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codectest certifies a codec.Codec[T] before it is used as a
+// MapState key or PriorityQueueState element:
+//
+//	func TestScoreCodec(t *testing.T) {
+//		codectest.RunCodecConformance(t, ScoreCodec{},
+//			func(r *rand.Rand) Score { return Score(r.Int63() - r.Int63()) },
+//			func(a, b Score) bool { return a < b })
+//	}
+//
+// Order is checked on the codec alone. A variable-size ordered codec such as
+// StringCodec keeps its order only as the last part of a composite key.
+package codectest
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+)
+
+// Options tunes a conformance run. The zero value is ready to use.
+type Options[T any] struct {
+	// Edges are checked in addition to generated values. Nil uses the built-in
+	// edge cases when T is a primitive type (min/max ints, NaN, -0.0, ...).
+	Edges []T
+	// Equal compares a decoded value with the original. Nil compares floats
+	// bit for bit and everything else with reflect.DeepEqual.
+	Equal func(a, b T) bool
+	// Samples is the number of generated values; 0 means 256.
+	Samples int
+	// Seed seeds the generator so failures are reproducible.
+	Seed int64
+}
+
+// RunCodecConformance checks c with values from gen plus the built-in edge
+// cases for T. less is the value order that an ordered codec's bytes must
+// follow; it may be nil when c.IsOrderedKeyCodec() is false.
+func RunCodecConformance[T any](t *testing.T, c codec.Codec[T], gen func(r *rand.Rand) T, less func(a, b T) bool) {
+	t.Helper()
+	RunCodecConformanceWithOptions(t, c, gen, less, Options[T]{})
+}
+
+// RunCodecConformanceWithOptions is RunCodecConformance with explicit options.
+func RunCodecConformanceWithOptions[T any](t *testing.T, c codec.Codec[T], gen func(r *rand.Rand) T, less func(a, b T) bool, opts Options[T]) {
+	t.Helper()
+	if c == nil {
+		t.Fatal("codec must not be nil")
+	}
+	values := sampleValues(gen, opts)
+	equal := opts.Equal
+	if equal == nil {
+		equal = defaultEqual[T]
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		checkRoundTrip(t, c, values, equal)
+	})
+	t.Run("EncodedSize", func(t *testing.T) {
+		checkEncodedSize(t, c, values)
+	})
+	t.Run("Order", func(t *testing.T) {
+		if !c.IsOrderedKeyCodec() {
+			t.Skip("codec is not ordered")
+		}
+		if less == nil {
+			t.Fatal("ordered codec requires a less function")
+		}
+		checkOrder(t, c, values, less)
+	})
+}
+
+func sampleValues[T any](gen func(r *rand.Rand) T, opts Options[T]) []T {
+	edges := opts.Edges
+	if edges == nil {
+		edges = defaultEdges[T]()
+	}
+	samples := opts.Samples
+	if samples <= 0 {
+		samples = 256
+	}
+	values := make([]T, 0, len(edges)+samples)
+	values = append(values, edges...)
+	if gen != nil {
+		r := rand.New(rand.NewSource(opts.Seed))
+		for i := 0; i < samples; i++ {
+			values = append(values, gen(r))
+		}
+	}
+	return values
+}
+
+func checkRoundTrip[T any](t *testing.T, c codec.Codec[T], values []T, equal func(a, b T) bool) {
+	for _, v := range values {
+		encoded := mustEncode(t, c, v)
+		decoded, err := c.Decode(bytes.Clone(encoded))
+		if err != nil {
+			t.Fatalf("Decode(Encode(%v)) error: %v (bytes %x)", v, err, encoded)
+		}
+		if !equal(decoded, v) {
+			t.Fatalf("Decode(Encode(%v)) = %v (bytes %x)", v, decoded, encoded)
+		}
+		again := mustEncode(t, c, decoded)
+		if !bytes.Equal(again, encoded) {
+			t.Fatalf("Encode is not stable for %v: %x then %x", v, encoded, again)
+		}
+	}
+}
+
+func checkEncodedSize[T any](t *testing.T, c codec.Codec[T], values []T) {
+	size, fixed := codec.FixedEncodedSize(c)
+	if !fixed {
+		t.Skip("codec is variable-size")
+	}
+	for _, v := range values {
+		encoded := mustEncode(t, c, v)
+		if len(encoded) != size {
+			t.Fatalf("len(Encode(%v)) = %d, EncodedSize() = %d", v, len(encoded), size)
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	encoded := mustEncode(t, c, values[0])
+	if _, err := c.Decode(encoded[:size-1]); err == nil {
+		t.Fatalf("Decode accepted a %d-byte payload for a %d-byte codec", size-1, size)
+	}
+	if _, err := c.Decode(append(encoded, 0)); err == nil {
+		t.Fatalf("Decode accepted a %d-byte payload for a %d-byte codec", size+1, size)
+	}
+}
+
+// checkOrder compares every pair: a strict weak order can put equivalent
+// values next to each other, so adjacent pairs after sorting are not enough.
+func checkOrder[T any](t *testing.T, c codec.Codec[T], values []T, less func(a, b T) bool) {
+	encoded := make([][]byte, len(values))
+	for i, v := range values {
+		encoded[i] = mustEncode(t, c, v)
+	}
+	for i := range values {
+		for j := range values {
+			if less(values[i], values[j]) && bytes.Compare(encoded[i], encoded[j]) >= 0 {
+				t.Fatalf("%v < %v but Encode gives %x >= %x", values[i], values[j], encoded[i], encoded[j])
+			}
+		}
+	}
+}
+
+func mustEncode[T any](t *testing.T, c codec.Codec[T], v T) []byte {
+	t.Helper()
+	encoded, err := c.Encode(v)
+	if err != nil {
+		t.Fatalf("Encode(%v) error: %v", v, err)
+	}
+	return encoded
+}
+
+// Float64Less orders float64 values by IEEE 754 totalOrder: -NaN < -Inf <
+// ... < -0.0 < +0.0 < ... < +Inf < +NaN. Use it as the less function for
+// codecs that must give every float, including NaN, a fixed place.
+func Float64Less(a, b float64) bool {
+	return totalOrderKey64(a) < totalOrderKey64(b)
+}
+
+// Float32Less is Float64Less for float32.
+func Float32Less(a, b float32) bool {
+	return totalOrderKey32(a) < totalOrderKey32(b)
+}
+
+func totalOrderKey64(f float64) int64 {
+	bits := int64(math.Float64bits(f))
+	if bits < 0 {
+		bits ^= math.MaxInt64
+	}
+	return bits
+}
+
+func totalOrderKey32(f float32) int32 {
+	bits := int32(math.Float32bits(f))
+	if bits < 0 {
+		bits ^= math.MaxInt32
+	}
+	return bits
+}
+
+func defaultEqual[T any](a, b T) bool {
+	switch av := any(a).(type) {
+	case float64:
+		return math.Float64bits(av) == math.Float64bits(any(b).(float64))
+	case float32:
+		return math.Float32bits(av) == math.Float32bits(any(b).(float32))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func defaultEdges[T any]() []T {
+	var zero T
+	var edges any
+	switch any(zero).(type) {
+	case bool:
+		edges = []bool{false, true}
+	case int8:
+		edges = []int8{math.MinInt8, math.MinInt8 + 1, -1, 0, 1, math.MaxInt8 - 1, math.MaxInt8}
+	case int16:
+		edges = []int16{math.MinInt16, math.MinInt16 + 1, -1, 0, 1, math.MaxInt16 - 1, math.MaxInt16}
+	case int32:
+		edges = []int32{math.MinInt32, math.MinInt32 + 1, -1, 0, 1, math.MaxInt32 - 1, math.MaxInt32}
+	case int64:
+		edges = []int64{math.MinInt64, math.MinInt64 + 1, -1, 0, 1, math.MaxInt64 - 1, math.MaxInt64}
+	case int:
+		edges = []int{math.MinInt, math.MinInt + 1, -1, 0, 1, math.MaxInt - 1, math.MaxInt}
+	case uint8:
+		edges = []uint8{0, 1, math.MaxInt8, math.MaxInt8 + 1, math.MaxUint8 - 1, math.MaxUint8}
+	case uint16:
+		edges = []uint16{0, 1, math.MaxInt16, math.MaxInt16 + 1, math.MaxUint16 - 1, math.MaxUint16}
+	case uint32:
+		edges = []uint32{0, 1, math.MaxInt32, math.MaxInt32 + 1, math.MaxUint32 - 1, math.MaxUint32}
+	case uint64:
+		edges = []uint64{0, 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64 - 1, math.MaxUint64}
+	case uint:
+		edges = []uint{0, 1, math.MaxInt, math.MaxInt + 1, math.MaxUint - 1, math.MaxUint}
+	case float64:
+		edges = []float64{
+			math.NaN(), math.Float64frombits(0xfff8000000000000),
+			math.Inf(1), math.Inf(-1),
+			math.Copysign(0, -1), 0,
+			math.MaxFloat64, -math.MaxFloat64,
+			math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64,
+			1, -1,
+		}
+	case float32:
+		edges = []float32{
+			float32(math.NaN()), math.Float32frombits(0xffc00000),
+			float32(math.Inf(1)), float32(math.Inf(-1)),
+			float32(math.Copysign(0, -1)), 0,
+			math.MaxFloat32, -math.MaxFloat32,
+			math.SmallestNonzeroFloat32, -math.SmallestNonzeroFloat32,
+			1, -1,
+		}
+	case string:
+		edges = []string{"", "\x00", "a", "a\x00", "ab", "b", "\xff", "\xff\xff", "日本"}
+	default:
+		return nil
+	}
+	return edges.([]T)
+}
@@ -154,3 +154,43 @@ func (s *KeyedAggregatingState[T, ACC, R]) Get() (R, bool, error) {
 func (s *KeyedAggregatingState[T, ACC, R]) Clear() error {
 	return s.factory.store.Delete(s.buildCK())
 }
+
+// MergeNamespaces merges the accumulators of primaryKey under the source
+// namespaces into the one under target and deletes the sources. Missing
+// accumulators are skipped; if none exist, nothing is written.
+func (f *KeyedAggregatingStateFactory[T, ACC, R]) MergeNamespaces(primaryKey []byte, target []byte, sources ...[]byte) error {
+	var merged ACC
+	found := false
+	for _, namespace := range append([][]byte{target}, sources...) {
+		ck := api.ComplexKey{KeyGroup: f.groupKey, Key: primaryKey, Namespace: namespace, UserKey: []byte{}}
+		raw, ok, err := f.store.Get(ck)
+		if err != nil {
+			return fmt.Errorf("failed to get accumulator: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		acc, err := f.accCodec.Decode(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decode accumulator: %w", err)
+		}
+		if found {
+			merged = f.aggFunc.Merge(merged, acc)
+		} else {
+			merged, found = acc, true
+		}
+	}
+	for _, namespace := range sources {
+		if err := f.store.Delete(api.ComplexKey{KeyGroup: f.groupKey, Key: primaryKey, Namespace: namespace, UserKey: []byte{}}); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return nil
+	}
+	encoded, err := f.accCodec.Encode(merged)
+	if err != nil {
+		return fmt.Errorf("failed to encode merged accumulator: %w", err)
+	}
+	return f.store.Put(api.ComplexKey{KeyGroup: f.groupKey, Key: primaryKey, Namespace: target, UserKey: []byte{}}, encoded)
+}
@@ -144,20 +144,21 @@ func (s *KeyedPriorityQueueState[V]) Poll() (V, bool, error) {
 	if err != nil || !found {
 		return val, found, err
 	}
+	return val, true, s.Remove(val)
+}
 
-	userKey, err := s.factory.valueCodec.Encode(val)
+// Remove deletes value from the queue; removing a value that is not queued is a no-op.
+func (s *KeyedPriorityQueueState[V]) Remove(value V) error {
+	userKey, err := s.factory.valueCodec.Encode(value)
 	if err != nil {
-		return val, true, fmt.Errorf("encode pq element for delete failed: %w", err)
+		return fmt.Errorf("encode pq element for delete failed: %w", err)
 	}
-	ck := api.ComplexKey{
+	return s.factory.store.Delete(api.ComplexKey{
 		KeyGroup:  s.factory.groupKey,
 		Key:       s.primaryKey,
 		Namespace: s.namespace,
 		UserKey:   userKey,
-	}
-
-	err = s.factory.store.Delete(ck)
-	return val, true, err
+	})
 }
 
 func (s *KeyedPriorityQueueState[V]) Clear() error {
@@ -149,3 +149,46 @@ func (s *KeyedReducingState[V]) Get() (V, bool, error) {
 func (s *KeyedReducingState[V]) Clear() error {
 	return s.factory.store.Delete(s.buildCK())
 }
+
+// MergeNamespaces reduces the values of primaryKey under the source
+// namespaces into the one under target and deletes the sources. Missing
+// values are skipped; if none exist, nothing is written.
+func (f *KeyedReducingStateFactory[V]) MergeNamespaces(primaryKey []byte, target []byte, sources ...[]byte) error {
+	var merged V
+	found := false
+	for _, namespace := range append([][]byte{target}, sources...) {
+		ck := api.ComplexKey{KeyGroup: f.groupKey, Key: primaryKey, Namespace: namespace, UserKey: []byte{}}
+		raw, ok, err := f.store.Get(ck)
+		if err != nil {
+			return fmt.Errorf("failed to get value for reducing state: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		value, err := f.valueCodec.Decode(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decode value: %w", err)
+		}
+		if !found {
+			merged, found = value, true
+			continue
+		}
+		merged, err = f.reduceFunc(merged, value)
+		if err != nil {
+			return fmt.Errorf("error in user reduce function: %w", err)
+		}
+	}
+	for _, namespace := range sources {
+		if err := f.store.Delete(api.ComplexKey{KeyGroup: f.groupKey, Key: primaryKey, Namespace: namespace, UserKey: []byte{}}); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return nil
+	}
+	encoded, err := f.valueCodec.Encode(merged)
+	if err != nil {
+		return fmt.Errorf("failed to encode reduced value: %w", err)
+	}
+	return f.store.Put(api.ComplexKey{KeyGroup: f.groupKey, Key: primaryKey, Namespace: target, UserKey: []byte{}}, encoded)
+}
@@ -0,0 +1,186 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ops
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/typed"
+	"github.com/functionstream/function-stream/go-sdk-advanced/watermark"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+const (
+	// WatermarkStore is the store in which a Driver reading more than one
+	// source keeps its watermark.Tracker.
+	WatermarkStore = "ops-watermarks"
+	// ConfigSources is the init config key listing the source IDs, separated
+	// by commas, of a pipeline built without From. It is ignored when From
+	// is set.
+	ConfigSources = "ops.sources"
+)
+
+// Driver runs a Pipeline as an api.Driver. Records are decoded with the input
+// codec, and each value the pipeline produces is encoded with the output codec
+// and emitted on the pipeline's target. The sources come from From or, failing
+// that, from ConfigSources. With several sources the driver emits the minimum
+// watermark over all of them, which never decreases; with one, watermarks are
+// forwarded unchanged. A pipeline that declares no sources is treated as
+// reading one: a record or watermark from a second source fails with
+// ErrRuntimeInvalidConfig, since its watermark could not be combined.
+type Driver[In, Out any] struct {
+	*typed.TypedDriver[In, Out]
+	pipeline   *Pipeline[In, Out]
+	sources    []uint32
+	watermarks *watermark.Tracker
+	// first is the only source an undeclared pipeline has seen.
+	first     uint32
+	seenFirst bool
+}
+
+// Driver builds a driver for p with the given codecs.
+func (p *Pipeline[In, Out]) Driver(inCodec codec.Codec[In], outCodec codec.Codec[Out]) (*Driver[In, Out], error) {
+	inner, err := typed.NewTypedDriver[In, Out](&pipelineProcessor[In, Out]{pipeline: p}, inCodec, outCodec)
+	if err != nil {
+		return nil, err
+	}
+	return &Driver[In, Out]{TypedDriver: inner, pipeline: p}, nil
+}
+
+// DriverAutoCodec builds a driver for p with the default codecs for In and Out.
+func (p *Pipeline[In, Out]) DriverAutoCodec() (*Driver[In, Out], error) {
+	inner, err := typed.NewTypedDriverAutoCodec[In, Out](&pipelineProcessor[In, Out]{pipeline: p})
+	if err != nil {
+		return nil, err
+	}
+	return &Driver[In, Out]{TypedDriver: inner, pipeline: p}, nil
+}
+
+// OnDecodeError sets the handler for undecodable records; see
+// typed.TypedDriver.OnDecodeError.
+func (d *Driver[In, Out]) OnDecodeError(handler typed.DecodeErrorHandler) *Driver[In, Out] {
+	d.TypedDriver.OnDecodeError(handler)
+	return d
+}
+
+// Init resolves the sources, runs the pipeline's OnInit functions and, when
+// there is more than one source, loads the watermark tracker from
+// WatermarkStore.
+func (d *Driver[In, Out]) Init(ctx api.Context, config map[string]string) error {
+	sources, err := d.pipeline.resolveSources(config)
+	if err != nil {
+		return err
+	}
+	d.sources, d.watermarks, d.seenFirst = sources, nil, false
+	if err := d.TypedDriver.Init(ctx, config); err != nil {
+		return err
+	}
+	if len(sources) <= 1 {
+		return nil
+	}
+	tracker, err := watermark.NewTracker(ctx, WatermarkStore, watermark.TrackerOptions{Sources: sources})
+	if err != nil {
+		return err
+	}
+	d.watermarks = tracker
+	return nil
+}
+
+func (d *Driver[In, Out]) Process(ctx api.Context, sourceID uint32, data []byte) error {
+	accepted, err := d.accept(sourceID)
+	if !accepted {
+		return err
+	}
+	return d.TypedDriver.Process(ctx, sourceID, data)
+}
+
+func (d *Driver[In, Out]) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64) error {
+	accepted, err := d.accept(sourceID)
+	if !accepted {
+		return err
+	}
+	if len(d.sources) > 1 {
+		if d.watermarks == nil {
+			return api.NewError(api.ErrRuntimeNotInitialized, "pipeline driver used before Init")
+		}
+		return d.watermarks.ProcessWatermark(ctx, sourceID, watermark, d.pipeline.target)
+	}
+	return ctx.EmitWatermark(d.pipeline.target, watermark)
+}
+
+// accept reports whether input from sourceID goes through the pipeline.
+// Sources outside a declared list are ignored; a second source of an
+// undeclared pipeline is an error.
+func (d *Driver[In, Out]) accept(sourceID uint32) (bool, error) {
+	if len(d.sources) > 0 {
+		return slices.Contains(d.sources, sourceID), nil
+	}
+	if !d.seenFirst {
+		d.first, d.seenFirst = sourceID, true
+	}
+	if sourceID != d.first {
+		return false, api.NewError(api.ErrRuntimeInvalidConfig,
+			"pipeline reads source %d besides source %d; declare its sources with From or %s", sourceID, d.first, ConfigSources)
+	}
+	return true, nil
+}
+
+// resolveSources returns the sources set with From, or else those listed
+// under ConfigSources; nil means none were declared.
+func (p *Pipeline[In, Out]) resolveSources(config map[string]string) ([]uint32, error) {
+	if len(p.sources) > 0 {
+		return p.sources, nil
+	}
+	raw := strings.TrimSpace(config[ConfigSources])
+	if raw == "" {
+		return nil, nil
+	}
+	var sources []uint32
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return nil, api.NewError(api.ErrRuntimeInvalidConfig,
+				"%s must list source IDs separated by commas, got %q", ConfigSources, raw)
+		}
+		if !slices.Contains(sources, uint32(id)) {
+			sources = append(sources, uint32(id))
+		}
+	}
+	return sources, nil
+}
+
+// pipelineProcessor is the typed.TypedProcessor behind Driver. It embeds
+// api.BaseDriver so TypedDriver uses it for the lifecycle calls.
+type pipelineProcessor[In, Out any] struct {
+	api.BaseDriver
+	pipeline *Pipeline[In, Out]
+}
+
+func (p *pipelineProcessor[In, Out]) Init(ctx api.Context, config map[string]string) error {
+	for _, fn := range p.pipeline.inits {
+		if err := fn(ctx, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pipelineProcessor[In, Out]) ProcessTyped(ctx typed.TypedContext[Out], _ uint32, in In) error {
+	target := p.pipeline.target
+	return p.pipeline.run(ctx, in, func(out Out) error {
+		return ctx.EmitTyped(target, out)
+	})
+}
@@ -0,0 +1,112 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ops_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/ops"
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/fstest"
+)
+
+func TestDriverWatermarks(t *testing.T) {
+	passAll := func(string) bool { return true }
+	steps := []fstest.Step{
+		fstest.Watermark(1, 100),
+		fstest.Watermark(0, 10),
+		fstest.Watermark(0, 50),
+		fstest.Watermark(1, 120),
+		fstest.Watermark(0, 200),
+	}
+	tests := []struct {
+		name     string
+		pipeline *ops.Pipeline[string, string]
+		config   map[string]string
+		want     []uint64
+		wantErr  api.ErrorCode
+	}{
+		{
+			name:     "single source forwards unchanged",
+			pipeline: ops.Filter(passAll).From(1),
+			want:     []uint64{100, 120},
+		},
+		{
+			name:     "From sources combine to a minimum",
+			pipeline: ops.Filter(passAll).From(0, 1),
+			want:     []uint64{10, 50, 120},
+		},
+		{
+			name:     "config sources combine to a minimum",
+			pipeline: ops.Filter(passAll),
+			config:   map[string]string{ops.ConfigSources: "0, 1"},
+			want:     []uint64{10, 50, 120},
+		},
+		{
+			name:     "undeclared pipeline rejects a second source",
+			pipeline: ops.Filter(passAll),
+			want:     []uint64{100},
+			wantErr:  api.ErrRuntimeInvalidConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, err := tt.pipeline.DriverAutoCodec()
+			if err != nil {
+				t.Fatalf("DriverAutoCodec: %v", err)
+			}
+			h := fstest.NewHarness(driver)
+			if _, err := h.Step(0, fstest.Init(tt.config)); err != nil {
+				t.Fatalf("init: %v", err)
+			}
+			var got []uint64
+			var stepErr error
+			for idx, step := range steps {
+				result, err := h.Step(idx+1, step)
+				for _, e := range result.Emits {
+					if e.Kind == fstest.EmitWatermark {
+						got = append(got, e.Watermark)
+					}
+				}
+				if err != nil {
+					stepErr = err
+					break
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("watermarks = %v, want %v", got, tt.want)
+			}
+			var sdkErr *api.SDKError
+			switch {
+			case tt.wantErr == "" && stepErr != nil:
+				t.Errorf("unexpected error: %v", stepErr)
+			case tt.wantErr != "" && (!errors.As(stepErr, &sdkErr) || sdkErr.Code != tt.wantErr):
+				t.Errorf("error = %v, want code %s", stepErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDriverRejectsInvalidConfigSources(t *testing.T) {
+	driver, err := ops.Filter(func(string) bool { return true }).DriverAutoCodec()
+	if err != nil {
+		t.Fatalf("DriverAutoCodec: %v", err)
+	}
+	err = driver.Init(fstest.NewContext(nil), map[string]string{ops.ConfigSources: "0,x"})
+	var sdkErr *api.SDKError
+	if !errors.As(err, &sdkErr) || sdkErr.Code != api.ErrRuntimeInvalidConfig {
+		t.Fatalf("Init error = %v, want %s", err, api.ErrRuntimeInvalidConfig)
+	}
+}
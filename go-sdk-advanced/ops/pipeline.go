@@ -0,0 +1,182 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ops builds drivers for stateless and keyed transformations from
+// plain functions:
+//
+//	p := ops.Map(parse).Filter(valid).To(1)
+//	driver, err := p.DriverAutoCodec()
+//
+// Methods keep the value type; MapTo, FlatMapTo, Process and KeyBy change it.
+package ops
+
+import (
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// stage runs one input value through the pipeline, calling emit for each
+// output value.
+type stage[In, Out any] func(ctx api.Context, in In, emit func(Out) error) error
+
+// Pipeline is a chain of transformations from decoded In records to Out
+// values emitted on one target. Pipelines are immutable; every method
+// returns a new one.
+type Pipeline[In, Out any] struct {
+	run     stage[In, Out]
+	sources []uint32
+	target  uint32
+	inits   []func(ctx api.Context, config map[string]string) error
+}
+
+// Keyed is a value with the key KeyBy extracted from it. PrimaryKey is the
+// encoded key, ready for the keyed state factories' NewKeyed* methods.
+type Keyed[K, V any] struct {
+	Key        K
+	PrimaryKey []byte
+	Value      V
+}
+
+// Map starts a pipeline that transforms each record with fn.
+func Map[In, Out any](fn func(In) (Out, error)) *Pipeline[In, Out] {
+	return &Pipeline[In, Out]{run: mapStage[In, Out](fn)}
+}
+
+// Filter starts a pipeline that keeps the records pred accepts.
+func Filter[T any](pred func(T) bool) *Pipeline[T, T] {
+	return &Pipeline[T, T]{run: filterStage(pred)}
+}
+
+// FlatMap starts a pipeline that turns each record into zero or more values.
+func FlatMap[In, Out any](fn func(In) ([]Out, error)) *Pipeline[In, Out] {
+	return &Pipeline[In, Out]{run: flatMapStage[In, Out](fn)}
+}
+
+// Map appends a transformation that keeps the value type; see MapTo.
+func (p *Pipeline[In, Out]) Map(fn func(Out) (Out, error)) *Pipeline[In, Out] {
+	return then(p, mapStage[Out, Out](fn))
+}
+
+// Filter appends a stage that keeps the values pred accepts.
+func (p *Pipeline[In, Out]) Filter(pred func(Out) bool) *Pipeline[In, Out] {
+	return then(p, filterStage(pred))
+}
+
+// FlatMap appends a stage that turns each value into zero or more values of
+// the same type; see FlatMapTo.
+func (p *Pipeline[In, Out]) FlatMap(fn func(Out) ([]Out, error)) *Pipeline[In, Out] {
+	return then(p, flatMapStage[Out, Out](fn))
+}
+
+// From restricts the pipeline to records and watermarks from the given
+// sources; those from other sources are ignored. Without From the sources
+// come from the ConfigSources init key, or the pipeline reads a single
+// source; see Driver.
+func (p *Pipeline[In, Out]) From(sourceIDs ...uint32) *Pipeline[In, Out] {
+	next := *p
+	next.sources = append([]uint32(nil), sourceIDs...)
+	return &next
+}
+
+// To sets the target that values and watermarks are emitted on; the default
+// is 0.
+func (p *Pipeline[In, Out]) To(targetID uint32) *Pipeline[In, Out] {
+	next := *p
+	next.target = targetID
+	return &next
+}
+
+// OnInit registers fn to run in the driver's Init, e.g. to create keyed state
+// factories from ctx. Functions run in registration order.
+func (p *Pipeline[In, Out]) OnInit(fn func(ctx api.Context, config map[string]string) error) *Pipeline[In, Out] {
+	next := *p
+	next.inits = append(append([]func(api.Context, map[string]string) error(nil), p.inits...), fn)
+	return &next
+}
+
+// MapTo appends a transformation to a new value type.
+func MapTo[In, Mid, Out any](p *Pipeline[In, Mid], fn func(Mid) (Out, error)) *Pipeline[In, Out] {
+	return then(p, mapStage[Mid, Out](fn))
+}
+
+// FlatMapTo appends a stage that turns each value into zero or more values of
+// a new type.
+func FlatMapTo[In, Mid, Out any](p *Pipeline[In, Mid], fn func(Mid) ([]Out, error)) *Pipeline[In, Out] {
+	return then(p, flatMapStage[Mid, Out](fn))
+}
+
+// Process appends a general stage. fn gets the driver context, for state
+// access, and calls emit for each output value.
+func Process[In, Mid, Out any](p *Pipeline[In, Mid], fn func(ctx api.Context, value Mid, emit func(Out) error) error) *Pipeline[In, Out] {
+	return then(p, stage[Mid, Out](fn))
+}
+
+// KeyBy appends a stage that pairs each value with the key keyFn extracts,
+// encoded with keyCodec. Follow it with Process to use keyed state.
+func KeyBy[In, V, K any](p *Pipeline[In, V], keyFn func(V) K, keyCodec codec.Codec[K]) *Pipeline[In, Keyed[K, V]] {
+	return then(p, func(_ api.Context, value V, emit func(Keyed[K, V]) error) error {
+		key := keyFn(value)
+		primaryKey, err := keyCodec.Encode(key)
+		if err != nil {
+			return err
+		}
+		return emit(Keyed[K, V]{Key: key, PrimaryKey: primaryKey, Value: value})
+	})
+}
+
+func then[In, Mid, Out any](p *Pipeline[In, Mid], next stage[Mid, Out]) *Pipeline[In, Out] {
+	run := p.run
+	return &Pipeline[In, Out]{
+		run: func(ctx api.Context, in In, emit func(Out) error) error {
+			return run(ctx, in, func(mid Mid) error {
+				return next(ctx, mid, emit)
+			})
+		},
+		sources: p.sources,
+		target:  p.target,
+		inits:   p.inits,
+	}
+}
+
+func mapStage[In, Out any](fn func(In) (Out, error)) stage[In, Out] {
+	return func(_ api.Context, in In, emit func(Out) error) error {
+		out, err := fn(in)
+		if err != nil {
+			return err
+		}
+		return emit(out)
+	}
+}
+
+func filterStage[T any](pred func(T) bool) stage[T, T] {
+	return func(_ api.Context, in T, emit func(T) error) error {
+		if !pred(in) {
+			return nil
+		}
+		return emit(in)
+	}
+}
+
+func flatMapStage[In, Out any](fn func(In) ([]Out, error)) stage[In, Out] {
+	return func(_ api.Context, in In, emit func(Out) error) error {
+		outs, err := fn(in)
+		if err != nil {
+			return err
+		}
+		for _, out := range outs {
+			if err := emit(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timers
+
+import (
+	"github.com/functionstream/function-stream/go-sdk-advanced/keyed"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var (
+	timersKeyGroup = []byte("timers")
+	eventTimeQueue = []byte("event-time")
+	emptyBytes     = []byte{}
+)
+
+// OnTimerFunc is called for each timer that fires.
+type OnTimerFunc func(ctx api.Context, key, namespace []byte, timestamp uint64) error
+
+// EventTimeService keeps event-time timers in the store storeName. Create it
+// up front and Open it in Init; AdvanceWatermark fires due timers.
+type EventTimeService struct {
+	storeName string
+	onTimer   OnTimerFunc
+	queue     *keyed.KeyedPriorityQueueState[Timer]
+	watermark uint64
+	seen      bool
+}
+
+// NewEventTimeService creates a service whose timers live in storeName and
+// fire onTimer.
+func NewEventTimeService(storeName string, onTimer OnTimerFunc) *EventTimeService {
+	return &EventTimeService{storeName: storeName, onTimer: onTimer}
+}
+
+// Open binds the service to the store from ctx. Call it from Init; timers
+// saved in the store before a restart are kept.
+func (s *EventTimeService) Open(ctx api.Context) error {
+	factory, err := keyed.NewKeyedPriorityQueueStateFactoryFromContext[Timer](ctx, s.storeName, timersKeyGroup, timerCodec{})
+	if err != nil {
+		return err
+	}
+	queue, err := factory.NewKeyedPriorityQueue(eventTimeQueue, emptyBytes)
+	if err != nil {
+		return err
+	}
+	s.queue = queue
+	s.watermark, s.seen = 0, false
+	return nil
+}
+
+// CurrentWatermark returns the last watermark passed to AdvanceWatermark.
+func (s *EventTimeService) CurrentWatermark() (uint64, bool) {
+	return s.watermark, s.seen
+}
+
+// RegisterEventTimeTimer registers a timer that fires once the watermark
+// reaches timestamp. Registering the same timer again has no effect.
+func (s *EventTimeService) RegisterEventTimeTimer(key, namespace []byte, timestamp uint64) error {
+	if err := s.opened(); err != nil {
+		return err
+	}
+	return s.queue.Add(newTimer(key, namespace, timestamp))
+}
+
+// DeleteTimer removes a registered timer; deleting an unknown timer is a
+// no-op.
+func (s *EventTimeService) DeleteTimer(key, namespace []byte, timestamp uint64) error {
+	if err := s.opened(); err != nil {
+		return err
+	}
+	return s.queue.Remove(newTimer(key, namespace, timestamp))
+}
+
+// AdvanceWatermark fires every timer at or below watermark in timestamp
+// order, removing each before calling onTimer. Timers that onTimer registers
+// at or below watermark fire in the same call. A timer whose callback fails is
+// queued again and stops the call, so repeating the watermark fires it again.
+// A watermark lower than the current one fires nothing.
+func (s *EventTimeService) AdvanceWatermark(ctx api.Context, watermark uint64) error {
+	if err := s.opened(); err != nil {
+		return err
+	}
+	if s.seen && watermark < s.watermark {
+		return nil
+	}
+	s.watermark, s.seen = watermark, true
+	for {
+		timer, found, err := s.queue.Peek()
+		if err != nil {
+			return err
+		}
+		if !found || timer.Timestamp > watermark {
+			return nil
+		}
+		if err := s.queue.Remove(timer); err != nil {
+			return err
+		}
+		if err := s.onTimer(ctx, timer.Key, timer.Namespace, timer.Timestamp); err != nil {
+			if restoreErr := s.queue.Add(timer); restoreErr != nil {
+				return restoreErr
+			}
+			return err
+		}
+	}
+}
+
+func (s *EventTimeService) opened() error {
+	if s.queue == nil {
+		return api.NewError(api.ErrRuntimeNotInitialized, "event-time timers used before Open")
+	}
+	return nil
+}
+
+func newTimer(key, namespace []byte, timestamp uint64) Timer {
+	if namespace == nil {
+		namespace = emptyBytes
+	}
+	return Timer{Timestamp: timestamp, Key: key, Namespace: namespace}
+}
+
+// WithEventTime wraps driver so Init opens service before calling the
+// driver's Init, and ProcessWatermark fires due timers before passing the
+// watermark on, so timer output precedes a forwarded watermark. The result is
+// an api.BatchProcessor only if driver is one. With several inputs, combine
+// watermarks first (see watermark.Tracker) and call AdvanceWatermark from the
+// driver instead of using this wrapper.
+func WithEventTime(driver api.Driver, service *EventTimeService) api.Driver {
+	wrapped := &eventTimeDriver{Driver: driver, service: service}
+	if _, ok := driver.(api.BatchProcessor); ok {
+		return &batchEventTimeDriver{eventTimeDriver: wrapped}
+	}
+	return wrapped
+}
+
+type eventTimeDriver struct {
+	api.Driver
+	service *EventTimeService
+}
+
+type batchEventTimeDriver struct {
+	*eventTimeDriver
+}
+
+func (d *eventTimeDriver) Init(ctx api.Context, config map[string]string) error {
+	if err := d.service.Open(ctx); err != nil {
+		return err
+	}
+	return d.Driver.Init(ctx, config)
+}
+
+func (d *batchEventTimeDriver) ProcessBatch(ctx api.Context, sourceID uint32, records [][]byte) error {
+	return d.Driver.(api.BatchProcessor).ProcessBatch(ctx, sourceID, records)
+}
+
+func (d *eventTimeDriver) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64) error {
+	if err := d.service.AdvanceWatermark(ctx, watermark); err != nil {
+		return err
+	}
+	return d.Driver.ProcessWatermark(ctx, sourceID, watermark)
+}
@@ -0,0 +1,127 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timers_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/timers"
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/fstest"
+)
+
+var errCallback = errors.New("callback failed")
+
+func TestEventTimeServiceAdvanceWatermark(t *testing.T) {
+	tests := []struct {
+		name       string
+		timers     []uint64
+		failOnce   uint64
+		watermarks []uint64
+		want       []uint64
+		wantErrs   int
+	}{
+		{
+			name:       "fires due timers in timestamp order",
+			timers:     []uint64{30, 10, 20},
+			watermarks: []uint64{25, 40},
+			want:       []uint64{10, 20, 30},
+		},
+		{
+			name:       "duplicate registration fires once",
+			timers:     []uint64{10, 10},
+			watermarks: []uint64{10},
+			want:       []uint64{10},
+		},
+		{
+			name:       "lower watermark fires nothing",
+			timers:     []uint64{10, 20},
+			watermarks: []uint64{15, 5, 20},
+			want:       []uint64{10, 20},
+		},
+		{
+			name:       "failed callback fires again on the same watermark",
+			timers:     []uint64{10, 20, 30},
+			failOnce:   20,
+			watermarks: []uint64{25, 25, 30},
+			want:       []uint64{10, 20, 30},
+			wantErrs:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fired []uint64
+			failed := false
+			service := timers.NewEventTimeService("timers", func(_ api.Context, _, _ []byte, ts uint64) error {
+				if ts == tt.failOnce && !failed {
+					failed = true
+					return errCallback
+				}
+				fired = append(fired, ts)
+				return nil
+			})
+			ctx := fstest.NewContext(nil)
+			if err := service.Open(ctx); err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			for _, ts := range tt.timers {
+				if err := service.RegisterEventTimeTimer([]byte("k"), nil, ts); err != nil {
+					t.Fatalf("RegisterEventTimeTimer(%d): %v", ts, err)
+				}
+			}
+			errs := 0
+			for _, wm := range tt.watermarks {
+				if err := service.AdvanceWatermark(ctx, wm); err != nil {
+					if !errors.Is(err, errCallback) {
+						t.Fatalf("AdvanceWatermark(%d): %v", wm, err)
+					}
+					errs++
+				}
+			}
+			if !slices.Equal(fired, tt.want) {
+				t.Errorf("fired = %v, want %v", fired, tt.want)
+			}
+			if errs != tt.wantErrs {
+				t.Errorf("callback errors = %d, want %d", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestEventTimeServiceDeleteTimer(t *testing.T) {
+	var fired []uint64
+	service := timers.NewEventTimeService("timers", func(_ api.Context, _, _ []byte, ts uint64) error {
+		fired = append(fired, ts)
+		return nil
+	})
+	ctx := fstest.NewContext(nil)
+	if err := service.Open(ctx); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, ts := range []uint64{10, 20} {
+		if err := service.RegisterEventTimeTimer([]byte("k"), []byte("ns"), ts); err != nil {
+			t.Fatalf("RegisterEventTimeTimer(%d): %v", ts, err)
+		}
+	}
+	if err := service.DeleteTimer([]byte("k"), []byte("ns"), 10); err != nil {
+		t.Fatalf("DeleteTimer: %v", err)
+	}
+	if err := service.AdvanceWatermark(ctx, 20); err != nil {
+		t.Fatalf("AdvanceWatermark: %v", err)
+	}
+	if !slices.Equal(fired, []uint64{20}) {
+		t.Errorf("fired = %v, want [20]", fired)
+	}
+}
@@ -0,0 +1,239 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timers
+
+import (
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/keyed"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var processingTimeQueue = []byte("processing-time")
+
+// ProcessingTimeService runs processing-time timers and periodic tasks
+// against a Clock. Nothing runs on its own: Fire runs whatever is due, and
+// WithProcessingTime calls it whenever the runtime enters the driver,
+// including on the host's periodic heartbeat. Timer timestamps are Unix
+// milliseconds. Timers are kept in the store storeName and survive
+// restarts; periodic tasks are registered in code and are not.
+type ProcessingTimeService struct {
+	storeName string
+	clock     Clock
+	onTimer   OnTimerFunc
+	queue     *keyed.KeyedPriorityQueueState[Timer]
+	tasks     []*periodicTask
+
+	// head caches the earliest timer timestamp so Fire does not scan the
+	// store on every call; headKnown is false when it must be re-read.
+	head      uint64
+	hasHead   bool
+	headKnown bool
+}
+
+type periodicTask struct {
+	interval time.Duration
+	fn       func(ctx api.Context) error
+	next     time.Time
+}
+
+// NewProcessingTimeService creates a service whose timers live in storeName
+// and fire onTimer. A nil clock uses SystemClock.
+func NewProcessingTimeService(storeName string, clock Clock, onTimer OnTimerFunc) *ProcessingTimeService {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &ProcessingTimeService{storeName: storeName, clock: clock, onTimer: onTimer}
+}
+
+// Open binds the service to the store from ctx and schedules every periodic
+// task one interval from now. Call it from Init.
+func (s *ProcessingTimeService) Open(ctx api.Context) error {
+	factory, err := keyed.NewKeyedPriorityQueueStateFactoryFromContext[Timer](ctx, s.storeName, timersKeyGroup, timerCodec{})
+	if err != nil {
+		return err
+	}
+	queue, err := factory.NewKeyedPriorityQueue(processingTimeQueue, emptyBytes)
+	if err != nil {
+		return err
+	}
+	s.queue = queue
+	s.headKnown = false
+	now := s.clock.Now()
+	for _, task := range s.tasks {
+		task.next = now.Add(task.interval)
+	}
+	return nil
+}
+
+// Now returns the clock's time in Unix milliseconds.
+func (s *ProcessingTimeService) Now() uint64 {
+	return uint64(s.clock.Now().UnixMilli())
+}
+
+// Every registers fn to run every interval. A task that falls behind runs
+// once and is rescheduled one interval after that run, so missed runs are
+// not replayed. Register tasks before Open.
+func (s *ProcessingTimeService) Every(interval time.Duration, fn func(ctx api.Context) error) {
+	s.tasks = append(s.tasks, &periodicTask{interval: interval, fn: fn, next: s.clock.Now().Add(interval)})
+}
+
+// RegisterProcessingTimeTimer registers a timer that fires once the clock
+// reaches timestamp (Unix milliseconds). Registering the same timer again has
+// no effect.
+func (s *ProcessingTimeService) RegisterProcessingTimeTimer(key, namespace []byte, timestamp uint64) error {
+	if err := s.opened(); err != nil {
+		return err
+	}
+	if err := s.queue.Add(newTimer(key, namespace, timestamp)); err != nil {
+		s.headKnown = false
+		return err
+	}
+	if s.headKnown && (!s.hasHead || timestamp < s.head) {
+		s.head, s.hasHead = timestamp, true
+	}
+	return nil
+}
+
+// DeleteTimer removes a registered timer; deleting an unknown timer is a
+// no-op.
+func (s *ProcessingTimeService) DeleteTimer(key, namespace []byte, timestamp uint64) error {
+	if err := s.opened(); err != nil {
+		return err
+	}
+	if timestamp == s.head {
+		s.headKnown = false
+	}
+	return s.queue.Remove(newTimer(key, namespace, timestamp))
+}
+
+// Fire reads the clock once, fires every timer due by then in timestamp
+// order, then runs the periodic tasks that are due, in registration order.
+// A timer whose callback fails is queued again and stops the call, so it
+// fires again, ahead of later timers, on the next Fire.
+func (s *ProcessingTimeService) Fire(ctx api.Context) error {
+	if err := s.opened(); err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	if err := s.fireTimers(ctx, uint64(now.UnixMilli())); err != nil {
+		return err
+	}
+	for _, task := range s.tasks {
+		if now.Before(task.next) {
+			continue
+		}
+		task.next = now.Add(task.interval)
+		if err := task.fn(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ProcessingTimeService) fireTimers(ctx api.Context, deadline uint64) error {
+	if s.headKnown && (!s.hasHead || s.head > deadline) {
+		return nil
+	}
+	for {
+		s.headKnown = false
+		timer, found, err := s.queue.Peek()
+		if err != nil {
+			return err
+		}
+		s.head, s.hasHead, s.headKnown = timer.Timestamp, found, true
+		if !found || timer.Timestamp > deadline {
+			return nil
+		}
+		s.headKnown = false
+		if err := s.queue.Remove(timer); err != nil {
+			return err
+		}
+		if err := s.onTimer(ctx, timer.Key, timer.Namespace, timer.Timestamp); err != nil {
+			if restoreErr := s.queue.Add(timer); restoreErr != nil {
+				return restoreErr
+			}
+			return err
+		}
+	}
+}
+
+func (s *ProcessingTimeService) opened() error {
+	if s.queue == nil {
+		return api.NewError(api.ErrRuntimeNotInitialized, "processing-time timers used before Open")
+	}
+	return nil
+}
+
+// WithProcessingTime wraps driver so due processing-time timers and periodic
+// tasks run on every CheckHeartbeat, Process, ProcessBatch and
+// ProcessWatermark, before the driver's own method. Init opens the service
+// before calling the driver's Init. Firing failures are not charged to the
+// record or watermark being handled: the driver's method still runs, and the
+// next CheckHeartbeat reports the driver unhealthy. The result is an
+// api.BatchProcessor only if driver is one.
+func WithProcessingTime(driver api.Driver, service *ProcessingTimeService) api.Driver {
+	wrapped := &processingTimeDriver{Driver: driver, service: service}
+	if _, ok := driver.(api.BatchProcessor); ok {
+		return &batchProcessingTimeDriver{processingTimeDriver: wrapped}
+	}
+	return wrapped
+}
+
+type processingTimeDriver struct {
+	api.Driver
+	service *ProcessingTimeService
+	// fireErr holds the first firing failure since the last CheckHeartbeat.
+	fireErr error
+}
+
+type batchProcessingTimeDriver struct {
+	*processingTimeDriver
+}
+
+func (d *processingTimeDriver) Init(ctx api.Context, config map[string]string) error {
+	if err := d.service.Open(ctx); err != nil {
+		return err
+	}
+	return d.Driver.Init(ctx, config)
+}
+
+func (d *processingTimeDriver) Process(ctx api.Context, sourceID uint32, data []byte) error {
+	d.fire(ctx)
+	return d.Driver.Process(ctx, sourceID, data)
+}
+
+// ProcessBatch fires once per batch.
+func (d *batchProcessingTimeDriver) ProcessBatch(ctx api.Context, sourceID uint32, records [][]byte) error {
+	d.fire(ctx)
+	return d.Driver.(api.BatchProcessor).ProcessBatch(ctx, sourceID, records)
+}
+
+func (d *processingTimeDriver) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64) error {
+	d.fire(ctx)
+	return d.Driver.ProcessWatermark(ctx, sourceID, watermark)
+}
+
+func (d *processingTimeDriver) CheckHeartbeat(ctx api.Context) bool {
+	d.fire(ctx)
+	failed := d.fireErr != nil
+	d.fireErr = nil
+	return !failed && d.Driver.CheckHeartbeat(ctx)
+}
+
+// fire runs what is due and keeps the first failure for CheckHeartbeat.
+func (d *processingTimeDriver) fire(ctx api.Context) {
+	if err := d.service.Fire(ctx); err != nil && d.fireErr == nil {
+		d.fireErr = err
+	}
+}
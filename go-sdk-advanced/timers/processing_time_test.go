@@ -0,0 +1,133 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timers_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/timers"
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/fstest"
+)
+
+func TestProcessingTimeServiceFire(t *testing.T) {
+	start := time.UnixMilli(1_000)
+	base := uint64(start.UnixMilli())
+	tests := []struct {
+		name     string
+		timers   []uint64
+		failOnce uint64
+		advances []time.Duration
+		want     []uint64
+		wantErrs int
+	}{
+		{
+			name:     "fires due timers in timestamp order",
+			timers:   []uint64{30, 10, 20},
+			advances: []time.Duration{5 * time.Millisecond, 20 * time.Millisecond, 10 * time.Millisecond},
+			want:     []uint64{10, 20, 30},
+		},
+		{
+			name:     "timer at the current time fires",
+			timers:   []uint64{10},
+			advances: []time.Duration{10 * time.Millisecond},
+			want:     []uint64{10},
+		},
+		{
+			name:     "failed callback fires again before later timers",
+			timers:   []uint64{10, 20, 30},
+			failOnce: 20,
+			advances: []time.Duration{25 * time.Millisecond, 0, 10 * time.Millisecond},
+			want:     []uint64{10, 20, 30},
+			wantErrs: 1,
+		},
+		{
+			name:     "failed callback waits for the next Fire",
+			timers:   []uint64{10, 20},
+			failOnce: 10,
+			advances: []time.Duration{30 * time.Millisecond, 0},
+			want:     []uint64{10, 20},
+			wantErrs: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fired []uint64
+			failed := false
+			clock := timers.NewManualClock(start)
+			service := timers.NewProcessingTimeService("timers", clock, func(_ api.Context, _, _ []byte, ts uint64) error {
+				if ts-base == tt.failOnce && !failed {
+					failed = true
+					return errCallback
+				}
+				fired = append(fired, ts-base)
+				return nil
+			})
+			ctx := fstest.NewContext(nil)
+			if err := service.Open(ctx); err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			for _, offset := range tt.timers {
+				if err := service.RegisterProcessingTimeTimer([]byte("k"), nil, base+offset); err != nil {
+					t.Fatalf("RegisterProcessingTimeTimer(%d): %v", offset, err)
+				}
+			}
+			errs := 0
+			for _, d := range tt.advances {
+				clock.Advance(d)
+				if err := service.Fire(ctx); err != nil {
+					if !errors.Is(err, errCallback) {
+						t.Fatalf("Fire: %v", err)
+					}
+					errs++
+				}
+			}
+			if !slices.Equal(fired, tt.want) {
+				t.Errorf("fired = %v, want %v", fired, tt.want)
+			}
+			if errs != tt.wantErrs {
+				t.Errorf("callback errors = %d, want %d", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestProcessingTimeServiceEvery(t *testing.T) {
+	clock := timers.NewManualClock(time.UnixMilli(0))
+	service := timers.NewProcessingTimeService("timers", clock, func(api.Context, []byte, []byte, uint64) error {
+		return nil
+	})
+	var runs []int64
+	service.Every(10*time.Millisecond, func(api.Context) error {
+		runs = append(runs, clock.Now().UnixMilli())
+		return nil
+	})
+	ctx := fstest.NewContext(nil)
+	if err := service.Open(ctx); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, d := range []time.Duration{5, 5, 5, 30, 5, 5} {
+		clock.Advance(d * time.Millisecond)
+		if err := service.Fire(ctx); err != nil {
+			t.Fatalf("Fire: %v", err)
+		}
+	}
+	// The run at 45ms is late and is not replayed; the next one is due at 55ms.
+	want := []int64{10, 45, 55}
+	if !slices.Equal(runs, want) {
+		t.Errorf("task ran at %v, want %v", runs, want)
+	}
+}
@@ -0,0 +1,64 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timers provides event-time timers that fire as the watermark
+// advances, and processing-time timers and periodic tasks that fire against
+// a Clock. Timers are kept in keyed priority queue state, so they are part
+// of checkpoints and survive restarts.
+package timers
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+)
+
+// Timer is a registered timer: a timestamp for a key and namespace.
+type Timer struct {
+	Timestamp uint64
+	Key       []byte
+	Namespace []byte
+}
+
+// timerCodec encodes a Timer as the big-endian timestamp, the key length,
+// the key and the namespace, so encoded timers sort by timestamp first.
+type timerCodec struct{}
+
+var _ codec.Codec[Timer] = timerCodec{}
+
+func (timerCodec) Encode(t Timer) ([]byte, error) {
+	out := make([]byte, 12, 12+len(t.Key)+len(t.Namespace))
+	binary.BigEndian.PutUint64(out, t.Timestamp)
+	binary.BigEndian.PutUint32(out[8:], uint32(len(t.Key)))
+	out = append(out, t.Key...)
+	return append(out, t.Namespace...), nil
+}
+
+func (timerCodec) Decode(data []byte) (Timer, error) {
+	if len(data) < 12 {
+		return Timer{}, fmt.Errorf("timer too short: %d bytes", len(data))
+	}
+	keyLen := binary.BigEndian.Uint32(data[8:])
+	if uint64(len(data)-12) < uint64(keyLen) {
+		return Timer{}, fmt.Errorf("timer key length %d exceeds %d remaining bytes", keyLen, len(data)-12)
+	}
+	rest := data[12:]
+	return Timer{
+		Timestamp: binary.BigEndian.Uint64(data),
+		Key:       append([]byte{}, rest[:keyLen]...),
+		Namespace: append([]byte{}, rest[keyLen:]...),
+	}, nil
+}
+
+func (timerCodec) EncodedSize() int        { return -1 }
+func (timerCodec) IsOrderedKeyCodec() bool { return true }
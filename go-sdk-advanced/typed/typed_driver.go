@@ -0,0 +1,157 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typed adapts processors that work on decoded values to api.Driver.
+// Input records are decoded and output values encoded with go-sdk-advanced
+// codecs.
+package typed
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// TypedProcessor handles one decoded input record. A TypedProcessor that also
+// implements api.Driver, usually by embedding api.BaseDriver, keeps its other
+// lifecycle methods; its Process method is replaced by the adapter's.
+type TypedProcessor[In, Out any] interface {
+	ProcessTyped(ctx TypedContext[Out], sourceID uint32, in In) error
+}
+
+// TypedContext is the context passed to ProcessTyped. EmitTyped encodes value
+// with the output codec; Emit still sends raw bytes.
+type TypedContext[Out any] interface {
+	api.Context
+	EmitTyped(targetID uint32, value Out) error
+}
+
+// DecodeErrorHandler decides what happens to a record the input codec could
+// not decode. Returning nil drops the record; returning an error fails the
+// Process call, which then follows the runtime's failure policy.
+type DecodeErrorHandler func(ctx api.Context, sourceID uint32, data []byte, err error) error
+
+// FailOnDecodeError is the default DecodeErrorHandler. It returns an
+// ErrDecode error.
+func FailOnDecodeError(_ api.Context, sourceID uint32, _ []byte, err error) error {
+	return api.NewError(api.ErrDecode, "decode record from source %d: %v", sourceID, err)
+}
+
+// SkipDecodeErrors is a DecodeErrorHandler that drops undecodable records.
+func SkipDecodeErrors(api.Context, uint32, []byte, error) error {
+	return nil
+}
+
+// DeadLetterDecodeErrors returns a DecodeErrorHandler that emits each
+// undecodable record to targetID in an api.DeadLetter envelope and carries on.
+// Unlike the dead_letter failure policy, other Process errors are unaffected.
+func DeadLetterDecodeErrors(targetID uint32) DecodeErrorHandler {
+	return func(ctx api.Context, sourceID uint32, data []byte, err error) error {
+		envelope, marshalErr := json.Marshal(api.DeadLetter{
+			SourceID:     sourceID,
+			Data:         data,
+			ErrorCode:    api.ErrDecode,
+			ErrorMessage: err.Error(),
+			Attempts:     1,
+		})
+		if marshalErr != nil {
+			return marshalErr
+		}
+		return ctx.Emit(targetID, envelope)
+	}
+}
+
+// TypedDriver is an api.Driver that decodes each record with the input codec
+// and hands it to a TypedProcessor. Lifecycle calls other than Process go to
+// the processor when it implements api.Driver, and to api.BaseDriver
+// otherwise.
+type TypedDriver[In, Out any] struct {
+	api.Driver
+	processor     TypedProcessor[In, Out]
+	inCodec       codec.Codec[In]
+	outCodec      codec.Codec[Out]
+	onDecodeError DecodeErrorHandler
+}
+
+// NewTypedDriver creates a TypedDriver for processor with the given codecs.
+func NewTypedDriver[In, Out any](processor TypedProcessor[In, Out], inCodec codec.Codec[In], outCodec codec.Codec[Out]) (*TypedDriver[In, Out], error) {
+	if processor == nil {
+		return nil, api.NewError(api.ErrRuntimeInvalidDriver, "typed processor must not be nil")
+	}
+	if inCodec == nil || outCodec == nil {
+		return nil, api.NewError(api.ErrRuntimeInvalidDriver, "typed driver codecs must not be nil")
+	}
+	lifecycle, ok := processor.(api.Driver)
+	if !ok {
+		lifecycle = api.BaseDriver{}
+	}
+	return &TypedDriver[In, Out]{
+		Driver:        lifecycle,
+		processor:     processor,
+		inCodec:       inCodec,
+		outCodec:      outCodec,
+		onDecodeError: FailOnDecodeError,
+	}, nil
+}
+
+// NewTypedDriverAutoCodec creates a TypedDriver with the default codecs for In
+// and Out.
+func NewTypedDriverAutoCodec[In, Out any](processor TypedProcessor[In, Out]) (*TypedDriver[In, Out], error) {
+	inCodec, err := codec.DefaultCodecFor[In]()
+	if err != nil {
+		return nil, err
+	}
+	outCodec, err := codec.DefaultCodecFor[Out]()
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedDriver(processor, inCodec, outCodec)
+}
+
+// OnDecodeError sets the handler for undecodable records; nil restores
+// FailOnDecodeError. It returns d for chaining.
+func (d *TypedDriver[In, Out]) OnDecodeError(handler DecodeErrorHandler) *TypedDriver[In, Out] {
+	if handler == nil {
+		handler = FailOnDecodeError
+	}
+	d.onDecodeError = handler
+	return d
+}
+
+func (d *TypedDriver[In, Out]) Process(ctx api.Context, sourceID uint32, data []byte) error {
+	in, err := d.inCodec.Decode(data)
+	if err != nil {
+		return d.onDecodeError(ctx, sourceID, data, err)
+	}
+	return d.processor.ProcessTyped(&typedContext[Out]{Context: ctx, outCodec: d.outCodec}, sourceID, in)
+}
+
+type typedContext[Out any] struct {
+	api.Context
+	outCodec codec.Codec[Out]
+}
+
+func (c *typedContext[Out]) EmitTyped(targetID uint32, value Out) error {
+	encoded, err := c.outCodec.Encode(value)
+	if err != nil {
+		return fmt.Errorf("encode output for target %d failed: %w", targetID, err)
+	}
+	return c.Emit(targetID, encoded)
+}
+
+// Flush sends the emits buffered by the runtime context, so api.Flush on the
+// context passed to ProcessTyped covers EmitTyped output too.
+func (c *typedContext[Out]) Flush() error {
+	return api.Flush(c.Context)
+}
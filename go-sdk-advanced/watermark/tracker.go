@@ -0,0 +1,194 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watermark combines the watermarks of several inputs into one output
+// watermark: the minimum over the active sources, never moving backwards.
+package watermark
+
+import (
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/structures"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// TrackerOptions configures a Tracker.
+type TrackerOptions struct {
+	// Sources lists the inputs that hold the combined watermark back until
+	// they report. Empty means the sources seen so far.
+	Sources []uint32
+	// IdleTimeout marks a source idle when it has had no record or watermark
+	// for this long, as checked by CheckIdle. Idle sources do not hold the
+	// combined watermark back. 0 disables idleness.
+	IdleTimeout time.Duration
+	// Now reads the clock used for idleness; nil uses time.Now.
+	Now func() time.Time
+}
+
+// Tracker keeps the latest watermark per source and the combined watermark
+// in a ValueState, so a restored driver never emits a lower watermark than
+// before the restart. Idleness is not persisted: after a restart every
+// source starts active.
+type Tracker struct {
+	opts    TrackerOptions
+	now     func() time.Time
+	state   *structures.ValueState[trackerState]
+	current trackerState
+	active  map[uint32]time.Time
+	idle    map[uint32]bool
+}
+
+// trackerState is the persisted part of a Tracker.
+type trackerState struct {
+	Sources  map[uint32]uint64 `json:"sources"`
+	Combined uint64            `json:"combined"`
+	Emitted  bool              `json:"emitted"`
+}
+
+// NewTracker creates a tracker whose state lives in the store storeName and
+// loads any state saved there. Use a store that holds nothing else.
+func NewTracker(ctx api.Context, storeName string, opts TrackerOptions) (*Tracker, error) {
+	state, err := structures.NewValueStateFromContext(ctx, storeName, codec.JSONCodec[trackerState]{})
+	if err != nil {
+		return nil, err
+	}
+	current, _, err := state.Value()
+	if err != nil {
+		return nil, err
+	}
+	if current.Sources == nil {
+		current.Sources = make(map[uint32]uint64)
+	}
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	t := &Tracker{
+		opts:    opts,
+		now:     now,
+		state:   state,
+		current: current,
+		active:  make(map[uint32]time.Time),
+		idle:    make(map[uint32]bool),
+	}
+	started := now()
+	for _, sourceID := range t.sourceIDs() {
+		t.active[sourceID] = started
+	}
+	return t, nil
+}
+
+// Current returns the combined watermark, if one has been reached.
+func (t *Tracker) Current() (uint64, bool) {
+	return t.current.Combined, t.current.Emitted
+}
+
+// MarkActive records activity on sourceID, e.g. from Process, so it is not
+// considered idle. A source that was idle becomes active again.
+func (t *Tracker) MarkActive(sourceID uint32) {
+	t.active[sourceID] = t.now()
+	delete(t.idle, sourceID)
+}
+
+// Observe records a watermark from sourceID. It returns the combined
+// watermark and whether it advanced; watermarks lower than the source's
+// previous one are ignored.
+func (t *Tracker) Observe(sourceID uint32, watermark uint64) (uint64, bool, error) {
+	t.MarkActive(sourceID)
+	if previous, ok := t.current.Sources[sourceID]; !ok || watermark > previous {
+		t.current.Sources[sourceID] = watermark
+	}
+	return t.advance(true)
+}
+
+// CheckIdle marks sources without activity for IdleTimeout as idle, which
+// can let the combined watermark advance. Call it from CheckHeartbeat.
+func (t *Tracker) CheckIdle() (uint64, bool, error) {
+	if t.opts.IdleTimeout <= 0 {
+		return t.current.Combined, false, nil
+	}
+	now := t.now()
+	for _, sourceID := range t.sourceIDs() {
+		if last, ok := t.active[sourceID]; ok && now.Sub(last) >= t.opts.IdleTimeout {
+			t.idle[sourceID] = true
+		}
+	}
+	return t.advance(false)
+}
+
+// ProcessWatermark observes the watermark and, when the combined watermark
+// advances, emits it to targetID.
+func (t *Tracker) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64, targetID uint32) error {
+	combined, advanced, err := t.Observe(sourceID, watermark)
+	if err != nil || !advanced {
+		return err
+	}
+	return ctx.EmitWatermark(targetID, combined)
+}
+
+// CheckHeartbeat runs CheckIdle and emits the combined watermark to targetID
+// when it advances.
+func (t *Tracker) CheckHeartbeat(ctx api.Context, targetID uint32) error {
+	combined, advanced, err := t.CheckIdle()
+	if err != nil || !advanced {
+		return err
+	}
+	return ctx.EmitWatermark(targetID, combined)
+}
+
+// advance recomputes the combined watermark and saves the state when it
+// advanced, or when dirty reports a per-source change.
+func (t *Tracker) advance(dirty bool) (uint64, bool, error) {
+	minimum, ok := t.minimum()
+	advanced := ok && (!t.current.Emitted || minimum > t.current.Combined)
+	if advanced {
+		t.current.Combined, t.current.Emitted = minimum, true
+	}
+	if advanced || dirty {
+		if err := t.state.Update(t.current); err != nil {
+			return t.current.Combined, false, err
+		}
+	}
+	return t.current.Combined, advanced, nil
+}
+
+// minimum returns the lowest watermark over active sources. It reports false
+// while an active source has not reported or every source is idle.
+func (t *Tracker) minimum() (uint64, bool) {
+	var minimum uint64
+	found := false
+	for _, sourceID := range t.sourceIDs() {
+		if t.idle[sourceID] {
+			continue
+		}
+		watermark, ok := t.current.Sources[sourceID]
+		if !ok {
+			return 0, false
+		}
+		if !found || watermark < minimum {
+			minimum, found = watermark, true
+		}
+	}
+	return minimum, found
+}
+
+func (t *Tracker) sourceIDs() []uint32 {
+	if len(t.opts.Sources) > 0 {
+		return t.opts.Sources
+	}
+	ids := make([]uint32, 0, len(t.current.Sources))
+	for sourceID := range t.current.Sources {
+		ids = append(ids, sourceID)
+	}
+	return ids
+}
@@ -0,0 +1,136 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/keyed"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var windowKeyGroup = []byte("window")
+
+// contents is the per-window state of an Operator: what has been added to a
+// key's window and the result it evaluates to.
+type contents[T any, R any] interface {
+	add(key, namespace []byte, value T) error
+	result(key, namespace []byte) (R, bool, error)
+	clear(key, namespace []byte) error
+	merge(key, target []byte, sources [][]byte) error
+}
+
+type openContents[T any, R any] func(ctx api.Context, storeName string) (contents[T, R], error)
+
+type aggregateContents[T any, ACC any, R any] struct {
+	factory *keyed.KeyedAggregatingStateFactory[T, ACC, R]
+}
+
+func openAggregate[T any, ACC any, R any](fn keyed.AggregateFunc[T, ACC, R], accCodec codec.Codec[ACC]) openContents[T, R] {
+	return func(ctx api.Context, storeName string) (contents[T, R], error) {
+		c := accCodec
+		if c == nil {
+			var err error
+			if c, err = codec.DefaultCodecFor[ACC](); err != nil {
+				return nil, err
+			}
+		}
+		factory, err := keyed.NewKeyedAggregatingStateFactoryFromContext(ctx, storeName, windowKeyGroup, c, fn)
+		if err != nil {
+			return nil, err
+		}
+		return aggregateContents[T, ACC, R]{factory: factory}, nil
+	}
+}
+
+func (c aggregateContents[T, ACC, R]) state(key, namespace []byte) (*keyed.KeyedAggregatingState[T, ACC, R], error) {
+	return c.factory.NewAggregatingState(key, string(namespace))
+}
+
+func (c aggregateContents[T, ACC, R]) add(key, namespace []byte, value T) error {
+	state, err := c.state(key, namespace)
+	if err != nil {
+		return err
+	}
+	return state.Add(value)
+}
+
+func (c aggregateContents[T, ACC, R]) result(key, namespace []byte) (R, bool, error) {
+	state, err := c.state(key, namespace)
+	if err != nil {
+		var zero R
+		return zero, false, err
+	}
+	return state.Get()
+}
+
+func (c aggregateContents[T, ACC, R]) clear(key, namespace []byte) error {
+	state, err := c.state(key, namespace)
+	if err != nil {
+		return err
+	}
+	return state.Clear()
+}
+
+func (c aggregateContents[T, ACC, R]) merge(key, target []byte, sources [][]byte) error {
+	return c.factory.MergeNamespaces(key, target, sources...)
+}
+
+type reduceContents[V any] struct {
+	factory *keyed.KeyedReducingStateFactory[V]
+}
+
+func openReduce[V any](fn keyed.ReduceFunc[V], valueCodec codec.Codec[V]) openContents[V, V] {
+	return func(ctx api.Context, storeName string) (contents[V, V], error) {
+		c := valueCodec
+		if c == nil {
+			var err error
+			if c, err = codec.DefaultCodecFor[V](); err != nil {
+				return nil, err
+			}
+		}
+		factory, err := keyed.NewKeyedReducingStateFactoryFromContext(ctx, storeName, windowKeyGroup, c, fn)
+		if err != nil {
+			return nil, err
+		}
+		return reduceContents[V]{factory: factory}, nil
+	}
+}
+
+func (c reduceContents[V]) add(key, namespace []byte, value V) error {
+	state, err := c.factory.NewReducingState(key, namespace)
+	if err != nil {
+		return err
+	}
+	return state.Add(value)
+}
+
+func (c reduceContents[V]) result(key, namespace []byte) (V, bool, error) {
+	state, err := c.factory.NewReducingState(key, namespace)
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+	return state.Get()
+}
+
+func (c reduceContents[V]) clear(key, namespace []byte) error {
+	state, err := c.factory.NewReducingState(key, namespace)
+	if err != nil {
+		return err
+	}
+	return state.Clear()
+}
+
+func (c reduceContents[V]) merge(key, target []byte, sources [][]byte) error {
+	return c.factory.MergeNamespaces(key, target, sources...)
+}
@@ -0,0 +1,262 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/keyed"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Element is a record kept in a Process window, with its event timestamp.
+type Element[T any] struct {
+	Timestamp uint64
+	Value     T
+}
+
+// ProcessFunc evaluates a window from all of its records, in arrival order.
+type ProcessFunc[T any, R any] func(key []byte, window Window, values []T) (R, error)
+
+// Evictor drops records from a Process window when it fires. EvictBefore
+// runs before the ProcessFunc and EvictAfter after it; each returns the
+// elements to keep, and the kept elements replace the window's contents.
+type Evictor[T any] interface {
+	EvictBefore(window Window, elements []Element[T]) []Element[T]
+	EvictAfter(window Window, elements []Element[T]) []Element[T]
+}
+
+type countEvictor[T any] struct {
+	count int
+}
+
+// CountEvictor keeps only the last count records of a window before it is
+// evaluated.
+func CountEvictor[T any](count int) Evictor[T] {
+	return countEvictor[T]{count: max(count, 0)}
+}
+
+func (e countEvictor[T]) EvictBefore(_ Window, elements []Element[T]) []Element[T] {
+	if len(elements) <= e.count {
+		return elements
+	}
+	return elements[len(elements)-e.count:]
+}
+
+func (countEvictor[T]) EvictAfter(_ Window, elements []Element[T]) []Element[T] {
+	return elements
+}
+
+type timeEvictor[T any] struct {
+	keep uint64
+}
+
+// TimeEvictor keeps only the records of a window whose timestamps are
+// within keep of the newest record's, before it is evaluated.
+func TimeEvictor[T any](keep time.Duration) Evictor[T] {
+	return timeEvictor[T]{keep: millis(keep)}
+}
+
+func (e timeEvictor[T]) EvictBefore(_ Window, elements []Element[T]) []Element[T] {
+	var newest uint64
+	for _, el := range elements {
+		newest = max(newest, el.Timestamp)
+	}
+	kept := elements[:0:0]
+	for _, el := range elements {
+		if el.Timestamp+e.keep > newest {
+			kept = append(kept, el)
+		}
+	}
+	return kept
+}
+
+func (timeEvictor[T]) EvictAfter(_ Window, elements []Element[T]) []Element[T] {
+	return elements
+}
+
+type afterEvictor[T any] struct {
+	evictor Evictor[T]
+}
+
+// EvictAfter runs evictor's EvictBefore after the window is evaluated
+// instead, so the result covers every record and the eviction only affects
+// later firings.
+func EvictAfter[T any](evictor Evictor[T]) Evictor[T] {
+	return afterEvictor[T]{evictor: evictor}
+}
+
+func (afterEvictor[T]) EvictBefore(_ Window, elements []Element[T]) []Element[T] {
+	return elements
+}
+
+func (e afterEvictor[T]) EvictAfter(w Window, elements []Element[T]) []Element[T] {
+	return e.evictor.EvictBefore(w, elements)
+}
+
+// elementCodec encodes an Element as its big-endian timestamp followed by
+// the value.
+type elementCodec[T any] struct {
+	value codec.Codec[T]
+}
+
+func (c elementCodec[T]) Encode(el Element[T]) ([]byte, error) {
+	encoded, err := c.value.Encode(el.Value)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 8, 8+len(encoded))
+	binary.BigEndian.PutUint64(out, el.Timestamp)
+	return append(out, encoded...), nil
+}
+
+func (c elementCodec[T]) Decode(data []byte) (Element[T], error) {
+	if len(data) < 8 {
+		return Element[T]{}, fmt.Errorf("window element too short: %d bytes", len(data))
+	}
+	value, err := c.value.Decode(data[8:])
+	if err != nil {
+		return Element[T]{}, err
+	}
+	return Element[T]{Timestamp: binary.BigEndian.Uint64(data), Value: value}, nil
+}
+
+func (c elementCodec[T]) EncodedSize() int {
+	if n, fixed := codec.FixedEncodedSize(c.value); fixed {
+		return 8 + n
+	}
+	return -1
+}
+
+func (elementCodec[T]) IsOrderedKeyCodec() bool { return false }
+
+// listContents keeps every record of a window in a KeyedListState and
+// evaluates the window with a ProcessFunc when it fires.
+type listContents[T any, R any] struct {
+	factory   *keyed.KeyedListStateFactory[Element[T]]
+	timestamp TimestampFunc[T]
+	fn        ProcessFunc[T, R]
+	evictor   Evictor[T]
+}
+
+func openProcess[T any, R any](fn ProcessFunc[T, R], valueCodec codec.Codec[T], timestamp TimestampFunc[T]) openContents[T, R] {
+	return func(ctx api.Context, storeName string) (contents[T, R], error) {
+		c := valueCodec
+		if c == nil {
+			var err error
+			if c, err = codec.DefaultCodecFor[T](); err != nil {
+				return nil, err
+			}
+		}
+		factory, err := keyed.NewKeyedListStateFactoryFromContext[Element[T]](ctx, storeName, windowKeyGroup, elementCodec[T]{value: c})
+		if err != nil {
+			return nil, err
+		}
+		return listContents[T, R]{factory: factory, timestamp: timestamp, fn: fn}, nil
+	}
+}
+
+func (c listContents[T, R]) withEvictor(evictor Evictor[T]) contents[T, R] {
+	c.evictor = evictor
+	return c
+}
+
+func (c listContents[T, R]) list(key, namespace []byte) (*keyed.KeyedListState[Element[T]], error) {
+	return keyed.NewKeyedListFromFactory(c.factory, key, namespace)
+}
+
+func (c listContents[T, R]) add(key, namespace []byte, value T) error {
+	list, err := c.list(key, namespace)
+	if err != nil {
+		return err
+	}
+	return list.Add(Element[T]{Timestamp: c.timestamp(value), Value: value})
+}
+
+func (c listContents[T, R]) result(key, namespace []byte) (R, bool, error) {
+	var zero R
+	list, err := c.list(key, namespace)
+	if err != nil {
+		return zero, false, err
+	}
+	elements, err := list.Get()
+	if err != nil || len(elements) == 0 {
+		return zero, false, err
+	}
+	w, err := windowCodec{}.Decode(namespace)
+	if err != nil {
+		return zero, false, err
+	}
+	count := len(elements)
+	if c.evictor != nil {
+		elements = c.evictor.EvictBefore(w, elements)
+	}
+	values := make([]T, len(elements))
+	for i, el := range elements {
+		values[i] = el.Value
+	}
+	result, err := c.fn(key, w, values)
+	if err != nil {
+		return zero, false, err
+	}
+	if c.evictor != nil {
+		elements = c.evictor.EvictAfter(w, elements)
+	}
+	switch {
+	case len(elements) == 0:
+		err = list.Clear()
+	case len(elements) != count:
+		err = list.Update(elements)
+	}
+	if err != nil {
+		return zero, false, err
+	}
+	return result, true, nil
+}
+
+func (c listContents[T, R]) clear(key, namespace []byte) error {
+	list, err := c.list(key, namespace)
+	if err != nil {
+		return err
+	}
+	return list.Clear()
+}
+
+func (c listContents[T, R]) merge(key, target []byte, sources [][]byte) error {
+	list, err := c.list(key, target)
+	if err != nil {
+		return err
+	}
+	for _, namespace := range sources {
+		source, err := c.list(key, namespace)
+		if err != nil {
+			return err
+		}
+		elements, err := source.Get()
+		if err != nil {
+			return err
+		}
+		if len(elements) > 0 {
+			if err := list.AddAll(elements); err != nil {
+				return err
+			}
+		}
+		if err := source.Clear(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
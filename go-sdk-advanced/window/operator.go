@@ -0,0 +1,400 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/keyed"
+	"github.com/functionstream/function-stream/go-sdk-advanced/timers"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var (
+	sessionsKeyGroup = []byte("window-sessions")
+	emptyBytes       = []byte{}
+)
+
+// TimestampFunc extracts the event timestamp, in Unix milliseconds, of a
+// record.
+type TimestampFunc[T any] func(value T) uint64
+
+// EmitFunc receives the result of a key's window when the window fires.
+type EmitFunc[R any] func(ctx api.Context, key []byte, window Window, result R) error
+
+// Operator accumulates keyed records into windows and emits results per key
+// and window when its Trigger fires; by default once, when the watermark
+// passes the window's end. Its state, including the pending timers, lives in
+// the store storeName, so each Operator needs a store of its own. Create it
+// up front, Open it in Init, call Add from Process and AdvanceWatermark from
+// ProcessWatermark. With a processing-time trigger, also call
+// FireProcessingTime, for example from CheckHeartbeat.
+//
+// A window's contents and trigger state are removed once the watermark
+// reaches its end. Records whose windows have all ended are late and are
+// dropped.
+type Operator[T any, R any] struct {
+	storeName string
+	assigner  Assigner
+	timestamp TimestampFunc[T]
+	emit      EmitFunc[R]
+	open      openContents[T, R]
+	trigger   Trigger
+	evictor   Evictor[T]
+	contents  contents[T, R]
+	sessions  *keyed.KeyedListStateFactory[Window]
+	services  *triggerServices
+}
+
+// Aggregate creates an Operator that folds each window's records with fn.
+func Aggregate[T any, ACC any, R any](storeName string, assigner Assigner, timestamp TimestampFunc[T], fn keyed.AggregateFunc[T, ACC, R], accCodec codec.Codec[ACC], emit EmitFunc[R]) *Operator[T, R] {
+	var open openContents[T, R]
+	if fn != nil && accCodec != nil {
+		open = openAggregate(fn, accCodec)
+	}
+	return newOperator(storeName, assigner, timestamp, open, emit)
+}
+
+// AggregateAutoCodec is Aggregate with the default codec for ACC.
+func AggregateAutoCodec[T any, ACC any, R any](storeName string, assigner Assigner, timestamp TimestampFunc[T], fn keyed.AggregateFunc[T, ACC, R], emit EmitFunc[R]) *Operator[T, R] {
+	var open openContents[T, R]
+	if fn != nil {
+		open = openAggregate[T, ACC, R](fn, nil)
+	}
+	return newOperator(storeName, assigner, timestamp, open, emit)
+}
+
+// Reduce creates an Operator that combines each window's records with fn.
+func Reduce[V any](storeName string, assigner Assigner, timestamp TimestampFunc[V], fn keyed.ReduceFunc[V], valueCodec codec.Codec[V], emit EmitFunc[V]) *Operator[V, V] {
+	var open openContents[V, V]
+	if fn != nil && valueCodec != nil {
+		open = openReduce(fn, valueCodec)
+	}
+	return newOperator(storeName, assigner, timestamp, open, emit)
+}
+
+// ReduceAutoCodec is Reduce with the default codec for V.
+func ReduceAutoCodec[V any](storeName string, assigner Assigner, timestamp TimestampFunc[V], fn keyed.ReduceFunc[V], emit EmitFunc[V]) *Operator[V, V] {
+	var open openContents[V, V]
+	if fn != nil {
+		open = openReduce(fn, nil)
+	}
+	return newOperator(storeName, assigner, timestamp, open, emit)
+}
+
+// Process creates an Operator that keeps every record of a window in a
+// KeyedListState and evaluates the window with fn each time it fires. Only
+// Process windows accept an Evictor.
+func Process[T any, R any](storeName string, assigner Assigner, timestamp TimestampFunc[T], fn ProcessFunc[T, R], valueCodec codec.Codec[T], emit EmitFunc[R]) *Operator[T, R] {
+	var open openContents[T, R]
+	if fn != nil && valueCodec != nil && timestamp != nil {
+		open = openProcess(fn, valueCodec, timestamp)
+	}
+	return newOperator(storeName, assigner, timestamp, open, emit)
+}
+
+// ProcessAutoCodec is Process with the default codec for T.
+func ProcessAutoCodec[T any, R any](storeName string, assigner Assigner, timestamp TimestampFunc[T], fn ProcessFunc[T, R], emit EmitFunc[R]) *Operator[T, R] {
+	var open openContents[T, R]
+	if fn != nil && timestamp != nil {
+		open = openProcess(fn, nil, timestamp)
+	}
+	return newOperator(storeName, assigner, timestamp, open, emit)
+}
+
+func newOperator[T any, R any](storeName string, assigner Assigner, timestamp TimestampFunc[T], open openContents[T, R], emit EmitFunc[R]) *Operator[T, R] {
+	o := &Operator[T, R]{storeName: storeName, assigner: assigner, timestamp: timestamp, emit: emit, open: open, trigger: EventTimeTrigger()}
+	o.services = &triggerServices{
+		eventTime:      timers.NewEventTimeService(storeName, o.onEventTime),
+		processingTime: timers.NewProcessingTimeService(storeName, nil, o.onProcessingTime),
+	}
+	return o
+}
+
+// WithTrigger replaces the default EventTimeTrigger. Call it before Open.
+func (o *Operator[T, R]) WithTrigger(trigger Trigger) *Operator[T, R] {
+	o.trigger = trigger
+	return o
+}
+
+// WithEvictor drops records from a Process window when it fires. Call it
+// before Open.
+func (o *Operator[T, R]) WithEvictor(evictor Evictor[T]) *Operator[T, R] {
+	o.evictor = evictor
+	return o
+}
+
+// WithClock sets the clock of processing-time triggers; the default is
+// timers.SystemClock. Call it before Open.
+func (o *Operator[T, R]) WithClock(clock timers.Clock) *Operator[T, R] {
+	o.services.processingTime = timers.NewProcessingTimeService(o.storeName, clock, o.onProcessingTime)
+	return o
+}
+
+// Open binds the operator to the store from ctx. Call it from Init; windows
+// and timers saved before a restart are kept.
+func (o *Operator[T, R]) Open(ctx api.Context) error {
+	if o.assigner == nil || o.timestamp == nil || o.open == nil || o.emit == nil || o.trigger == nil {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "window operator needs an assigner, a timestamp func, a window func with its codec, an emit func and a trigger")
+	}
+	if v, ok := o.assigner.(interface{ validate() error }); ok {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	state, err := o.open(ctx, o.storeName)
+	if err != nil {
+		return err
+	}
+	if o.evictor != nil {
+		evictable, ok := state.(interface {
+			withEvictor(Evictor[T]) contents[T, R]
+		})
+		if !ok {
+			return api.NewError(api.ErrRuntimeInvalidDriver, "window evictors need a Process window")
+		}
+		state = evictable.withEvictor(o.evictor)
+	}
+	if o.assigner.IsMerging() {
+		sessions, err := keyed.NewKeyedListStateFactoryFromContext[Window](ctx, o.storeName, sessionsKeyGroup, windowCodec{})
+		if err != nil {
+			return err
+		}
+		o.sessions = sessions
+	}
+	values, err := openTriggerValues(ctx, o.storeName)
+	if err != nil {
+		return err
+	}
+	if err := o.services.eventTime.Open(ctx); err != nil {
+		return err
+	}
+	if err := o.services.processingTime.Open(ctx); err != nil {
+		return err
+	}
+	o.services.values = values
+	o.contents = state
+	return nil
+}
+
+// CurrentWatermark returns the last watermark passed to AdvanceWatermark.
+func (o *Operator[T, R]) CurrentWatermark() (uint64, bool) {
+	return o.services.eventTime.CurrentWatermark()
+}
+
+// Add assigns value to its windows under key and runs the trigger for each.
+func (o *Operator[T, R]) Add(ctx api.Context, key []byte, value T) error {
+	if o.contents == nil {
+		return api.NewError(api.ErrRuntimeNotInitialized, "window operator used before Open")
+	}
+	if key == nil {
+		key = emptyBytes
+	}
+	timestamp := o.timestamp(value)
+	for _, w := range o.assigner.AssignWindows(timestamp) {
+		if o.assigner.IsMerging() {
+			merged, late, err := o.mergeWindow(key, w)
+			if err != nil {
+				return err
+			}
+			if late {
+				continue
+			}
+			w = merged
+		} else if o.isLate(w) {
+			continue
+		}
+		ns := w.namespace()
+		if err := o.contents.add(key, ns, value); err != nil {
+			return err
+		}
+		tc := o.triggerContext(key, w)
+		result, err := o.trigger.OnElement(tc, timestamp)
+		if err != nil {
+			return err
+		}
+		if err := o.apply(ctx, key, w, result); err != nil {
+			return err
+		}
+		if err := o.services.eventTime.RegisterEventTimeTimer(key, ns, w.MaxTimestamp()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeWindow merges w with every window of key it intersects and returns
+// the merged window, moving the contents and trigger state of the absorbed
+// windows to it. It reports late if the merged window has already ended.
+func (o *Operator[T, R]) mergeWindow(key []byte, w Window) (Window, bool, error) {
+	list, err := keyed.NewKeyedListFromFactory(o.sessions, key, emptyBytes)
+	if err != nil {
+		return w, false, err
+	}
+	windows, err := list.Get()
+	if err != nil {
+		return w, false, err
+	}
+	merged := w
+	var absorbed []Window
+	for changed := true; changed; {
+		changed = false
+		kept := windows[:0]
+		for _, existing := range windows {
+			if existing.Intersects(merged) {
+				merged = merged.Cover(existing)
+				absorbed = append(absorbed, existing)
+				changed = true
+			} else {
+				kept = append(kept, existing)
+			}
+		}
+		windows = kept
+	}
+	if o.isLate(merged) {
+		return merged, true, nil
+	}
+	if len(absorbed) == 1 && absorbed[0] == merged {
+		return merged, false, nil
+	}
+	var sources [][]byte
+	for _, existing := range absorbed {
+		if existing != merged {
+			sources = append(sources, existing.namespace())
+		}
+	}
+	if len(sources) > 0 {
+		if err := o.contents.merge(key, merged.namespace(), sources); err != nil {
+			return merged, false, err
+		}
+		tc := o.triggerContext(key, merged)
+		if err := o.trigger.OnMerge(tc, absorbed); err != nil {
+			return merged, false, err
+		}
+		for _, existing := range absorbed {
+			if existing == merged {
+				continue
+			}
+			if err := o.trigger.Clear(tc.ForWindow(existing)); err != nil {
+				return merged, false, err
+			}
+			if err := o.services.eventTime.DeleteTimer(key, existing.namespace(), existing.MaxTimestamp()); err != nil {
+				return merged, false, err
+			}
+		}
+	}
+	return merged, false, list.Update(append(windows, merged))
+}
+
+func (o *Operator[T, R]) isLate(w Window) bool {
+	watermark, seen := o.services.eventTime.CurrentWatermark()
+	return seen && w.MaxTimestamp() <= watermark
+}
+
+// AdvanceWatermark fires the event-time timers at or below watermark in
+// timestamp order, running the trigger for each and removing every window
+// that ends at or below watermark. A watermark lower than the current one
+// fires nothing.
+func (o *Operator[T, R]) AdvanceWatermark(ctx api.Context, watermark uint64) error {
+	return o.services.eventTime.AdvanceWatermark(ctx, watermark)
+}
+
+// FireProcessingTime fires the processing-time timers that are due by the
+// operator's clock.
+func (o *Operator[T, R]) FireProcessingTime(ctx api.Context) error {
+	return o.services.processingTime.Fire(ctx)
+}
+
+func (o *Operator[T, R]) triggerContext(key []byte, w Window) *TriggerContext {
+	return &TriggerContext{services: o.services, key: key, window: w}
+}
+
+// apply emits and purges the window as result says.
+func (o *Operator[T, R]) apply(ctx api.Context, key []byte, w Window, result TriggerResult) error {
+	ns := w.namespace()
+	if result.IsFire() {
+		value, found, err := o.contents.result(key, ns)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := o.emit(ctx, key, w, value); err != nil {
+				return err
+			}
+		}
+	}
+	if result.IsPurge() {
+		return o.contents.clear(key, ns)
+	}
+	return nil
+}
+
+func (o *Operator[T, R]) onEventTime(ctx api.Context, key, namespace []byte, timestamp uint64) error {
+	w, err := windowCodec{}.Decode(namespace)
+	if err != nil {
+		return err
+	}
+	tc := o.triggerContext(key, w)
+	result, err := o.trigger.OnEventTime(tc, timestamp)
+	if err != nil {
+		return err
+	}
+	if err := o.apply(ctx, key, w, result); err != nil {
+		return err
+	}
+	if timestamp != w.MaxTimestamp() {
+		return nil
+	}
+	if err := o.contents.clear(key, namespace); err != nil {
+		return err
+	}
+	if err := o.trigger.Clear(tc); err != nil {
+		return err
+	}
+	if o.sessions == nil {
+		return nil
+	}
+	return o.removeSession(key, w)
+}
+
+func (o *Operator[T, R]) onProcessingTime(ctx api.Context, key, namespace []byte, timestamp uint64) error {
+	w, err := windowCodec{}.Decode(namespace)
+	if err != nil {
+		return err
+	}
+	result, err := o.trigger.OnProcessingTime(o.triggerContext(key, w), timestamp)
+	if err != nil {
+		return err
+	}
+	return o.apply(ctx, key, w, result)
+}
+
+func (o *Operator[T, R]) removeSession(key []byte, removed Window) error {
+	list, err := keyed.NewKeyedListFromFactory(o.sessions, key, emptyBytes)
+	if err != nil {
+		return err
+	}
+	windows, err := list.Get()
+	if err != nil {
+		return err
+	}
+	kept := windows[:0]
+	for _, w := range windows {
+		if w != removed {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		return list.Clear()
+	}
+	return list.Update(kept)
+}
@@ -0,0 +1,211 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/window"
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/fstest"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+)
+
+// countRecords counts the records of a window; the records are their own
+// event timestamps.
+type countRecords struct{}
+
+func (countRecords) CreateAccumulator() int64      { return 0 }
+func (countRecords) Add(_ uint64, acc int64) int64 { return acc + 1 }
+func (countRecords) GetResult(acc int64) int64     { return acc }
+func (countRecords) Merge(a, b int64) int64        { return a + b }
+
+var errEmit = errors.New("emit failed")
+
+// opStep adds the record at add, or advances the watermark to watermark when
+// advance is set.
+type opStep struct {
+	add       uint64
+	watermark uint64
+	advance   bool
+}
+
+func add(ts uint64) opStep         { return opStep{add: ts} }
+func watermarkAt(wm uint64) opStep { return opStep{watermark: wm, advance: true} }
+
+func TestOperator(t *testing.T) {
+	tests := []struct {
+		name      string
+		assigner  window.Assigner
+		trigger   window.Trigger
+		failEmits int
+		steps     []opStep
+		want      []string
+		wantErrs  int
+	}{
+		{
+			name:     "tumbling fires when the watermark reaches the window end",
+			assigner: window.Tumbling(10 * time.Millisecond),
+			steps:    []opStep{add(1), add(5), add(12), watermarkAt(8), watermarkAt(9), watermarkAt(19)},
+			want:     []string{"[0, 10)=2", "[10, 20)=1"},
+		},
+		{
+			name:     "sliding near zero",
+			assigner: window.Sliding(10*time.Millisecond, 5*time.Millisecond),
+			steps:    []opStep{add(3), add(7), watermarkAt(20)},
+			want:     []string{"[0, 10)=2", "[5, 15)=1"},
+		},
+		{
+			name:     "late records are dropped",
+			assigner: window.Tumbling(10 * time.Millisecond),
+			steps:    []opStep{add(1), watermarkAt(9), add(3), add(9), add(10), watermarkAt(19)},
+			want:     []string{"[0, 10)=1", "[10, 20)=1"},
+		},
+		{
+			name:     "session merge bridges two windows",
+			assigner: window.Session(10 * time.Millisecond),
+			steps:    []opStep{add(0), add(18), add(9), watermarkAt(27)},
+			want:     []string{"[0, 28)=3"},
+		},
+		{
+			name:     "separate sessions fire separately",
+			assigner: window.Session(10 * time.Millisecond),
+			steps:    []opStep{add(0), add(11), watermarkAt(9), watermarkAt(20)},
+			want:     []string{"[0, 10)=1", "[11, 21)=1"},
+		},
+		{
+			name:     "late session is dropped",
+			assigner: window.Session(10 * time.Millisecond),
+			steps:    []opStep{add(5), watermarkAt(20), add(2), watermarkAt(30)},
+			want:     []string{"[5, 15)=1"},
+		},
+		{
+			name:     "count trigger state is cleaned up at the window end",
+			assigner: window.Tumbling(10 * time.Millisecond),
+			trigger:  window.CountTrigger(2),
+			steps:    []opStep{add(1), add(2), add(3), watermarkAt(9)},
+			want:     []string{"[0, 10)=2"},
+		},
+		{
+			name:      "failed emit fires again on the same watermark",
+			assigner:  window.Tumbling(10 * time.Millisecond),
+			failEmits: 1,
+			steps:     []opStep{add(1), add(12), watermarkAt(19), watermarkAt(19)},
+			want:      []string{"[0, 10)=1", "[10, 20)=1"},
+			wantErrs:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			failures := tt.failEmits
+			op := window.AggregateAutoCodec[uint64, int64, int64]("window", tt.assigner,
+				func(ts uint64) uint64 { return ts }, countRecords{},
+				func(_ api.Context, _ []byte, w window.Window, count int64) error {
+					if failures > 0 {
+						failures--
+						return errEmit
+					}
+					got = append(got, fmt.Sprintf("%v=%d", w, count))
+					return nil
+				})
+			if tt.trigger != nil {
+				op.WithTrigger(tt.trigger)
+			}
+			ctx := fstest.NewContext(nil)
+			if err := op.Open(ctx); err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			errs := 0
+			for _, step := range tt.steps {
+				var err error
+				if step.advance {
+					err = op.AdvanceWatermark(ctx, step.watermark)
+				} else {
+					err = op.Add(ctx, []byte("k"), step.add)
+				}
+				if err != nil {
+					if !errors.Is(err, errEmit) {
+						t.Fatalf("step %+v: %v", step, err)
+					}
+					errs++
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("emitted %v, want %v", got, tt.want)
+			}
+			if errs != tt.wantErrs {
+				t.Errorf("emit errors = %d, want %d", errs, tt.wantErrs)
+			}
+			if diff := memory.NewBackend().Snapshot().Diff(ctx.Backend().Snapshot()); len(diff) > 0 {
+				t.Errorf("state left after every window ended:\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestOperatorKeysAreIndependent(t *testing.T) {
+	var got []string
+	op := window.AggregateAutoCodec[uint64, int64, int64]("window", window.Session(10*time.Millisecond),
+		func(ts uint64) uint64 { return ts }, countRecords{},
+		func(_ api.Context, key []byte, w window.Window, count int64) error {
+			got = append(got, fmt.Sprintf("%s%v=%d", key, w, count))
+			return nil
+		})
+	ctx := fstest.NewContext(nil)
+	if err := op.Open(ctx); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, r := range []struct {
+		key string
+		ts  uint64
+	}{{"a", 0}, {"b", 5}, {"a", 8}} {
+		if err := op.Add(ctx, []byte(r.key), r.ts); err != nil {
+			t.Fatalf("Add(%s, %d): %v", r.key, r.ts, err)
+		}
+	}
+	if err := op.AdvanceWatermark(ctx, 30); err != nil {
+		t.Fatalf("AdvanceWatermark: %v", err)
+	}
+	want := []string{"b[5, 15)=1", "a[0, 18)=2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("emitted %v, want %v", got, want)
+	}
+}
+
+func TestOperatorBeforeOpen(t *testing.T) {
+	op := window.ReduceAutoCodec[uint64]("window", window.Tumbling(time.Second),
+		func(ts uint64) uint64 { return ts },
+		func(a, b uint64) (uint64, error) { return a + b, nil },
+		func(api.Context, []byte, window.Window, uint64) error { return nil })
+	err := op.Add(fstest.NewContext(nil), []byte("k"), 1)
+	var sdkErr *api.SDKError
+	if !errors.As(err, &sdkErr) || sdkErr.Code != api.ErrRuntimeNotInitialized {
+		t.Fatalf("Add before Open = %v, want %s", err, api.ErrRuntimeNotInitialized)
+	}
+}
+
+func TestOperatorRejectsInvalidAssigner(t *testing.T) {
+	op := window.AggregateAutoCodec[uint64, int64, int64]("window", window.Sliding(time.Second, 0),
+		func(ts uint64) uint64 { return ts }, countRecords{},
+		func(api.Context, []byte, window.Window, int64) error { return nil })
+	err := op.Open(fstest.NewContext(nil))
+	var sdkErr *api.SDKError
+	if !errors.As(err, &sdkErr) || sdkErr.Code != api.ErrRuntimeInvalidDriver {
+		t.Fatalf("Open = %v, want %s", err, api.ErrRuntimeInvalidDriver)
+	}
+}
@@ -0,0 +1,411 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window
+
+import (
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk-advanced/keyed"
+	"github.com/functionstream/function-stream/go-sdk-advanced/timers"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var triggerKeyGroup = []byte("window-trigger")
+
+// TriggerResult tells the Operator what to do with a window after a
+// Trigger call.
+type TriggerResult int
+
+const (
+	// Continue leaves the window as it is.
+	Continue TriggerResult = iota
+	// Fire emits the window's current result and keeps its contents.
+	Fire
+	// Purge drops the window's contents without emitting.
+	Purge
+	// FireAndPurge emits the window's result, then drops its contents.
+	FireAndPurge
+)
+
+// IsFire reports whether the window's result is emitted.
+func (r TriggerResult) IsFire() bool {
+	return r == Fire || r == FireAndPurge
+}
+
+// IsPurge reports whether the window's contents are dropped.
+func (r TriggerResult) IsPurge() bool {
+	return r == Purge || r == FireAndPurge
+}
+
+func (r TriggerResult) String() string {
+	switch r {
+	case Continue:
+		return "CONTINUE"
+	case Fire:
+		return "FIRE"
+	case Purge:
+		return "PURGE"
+	case FireAndPurge:
+		return "FIRE_AND_PURGE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Trigger decides when a key's window emits its result. OnElement runs after
+// each record is added to the window, OnEventTime and OnProcessingTime when
+// a timer the trigger registered fires. OnMerge runs when session windows
+// merge, with the context of the merged window, before the merged-away
+// windows are cleared. Clear runs when the window is removed and must delete
+// the trigger's timers and values.
+//
+// Whatever the trigger returns, a window's contents and trigger state are
+// removed once the watermark reaches its end; a trigger that should emit the
+// final result returns Fire from OnEventTime at Window.MaxTimestamp.
+type Trigger interface {
+	OnElement(tc *TriggerContext, timestamp uint64) (TriggerResult, error)
+	OnEventTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error)
+	OnProcessingTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error)
+	OnMerge(tc *TriggerContext, merged []Window) error
+	Clear(tc *TriggerContext) error
+}
+
+// triggerServices is what a TriggerContext reaches into; it belongs to the
+// Operator.
+type triggerServices struct {
+	eventTime      *timers.EventTimeService
+	processingTime *timers.ProcessingTimeService
+	values         *keyed.KeyedValueStateFactory[uint64]
+}
+
+// TriggerContext gives a Trigger the key and window it is called for, the
+// current times, timers scoped to the window, and named uint64 values kept
+// per key and window.
+type TriggerContext struct {
+	services *triggerServices
+	key      []byte
+	window   Window
+}
+
+// Key returns the key of the window.
+func (tc *TriggerContext) Key() []byte {
+	return tc.key
+}
+
+// Window returns the window the trigger is called for.
+func (tc *TriggerContext) Window() Window {
+	return tc.window
+}
+
+// ForWindow returns a context for another window of the same key, for
+// example one of the windows passed to OnMerge.
+func (tc *TriggerContext) ForWindow(w Window) *TriggerContext {
+	return &TriggerContext{services: tc.services, key: tc.key, window: w}
+}
+
+// CurrentWatermark returns the operator's watermark, or 0 before the first
+// one.
+func (tc *TriggerContext) CurrentWatermark() uint64 {
+	watermark, _ := tc.services.eventTime.CurrentWatermark()
+	return watermark
+}
+
+// CurrentProcessingTime returns the operator's clock in Unix milliseconds.
+func (tc *TriggerContext) CurrentProcessingTime() uint64 {
+	return tc.services.processingTime.Now()
+}
+
+// RegisterEventTimeTimer calls OnEventTime for this window once the
+// watermark reaches timestamp.
+func (tc *TriggerContext) RegisterEventTimeTimer(timestamp uint64) error {
+	return tc.services.eventTime.RegisterEventTimeTimer(tc.key, tc.window.namespace(), timestamp)
+}
+
+// DeleteEventTimeTimer removes a timer registered with
+// RegisterEventTimeTimer.
+func (tc *TriggerContext) DeleteEventTimeTimer(timestamp uint64) error {
+	return tc.services.eventTime.DeleteTimer(tc.key, tc.window.namespace(), timestamp)
+}
+
+// RegisterProcessingTimeTimer calls OnProcessingTime for this window once
+// the clock reaches timestamp.
+func (tc *TriggerContext) RegisterProcessingTimeTimer(timestamp uint64) error {
+	return tc.services.processingTime.RegisterProcessingTimeTimer(tc.key, tc.window.namespace(), timestamp)
+}
+
+// DeleteProcessingTimeTimer removes a timer registered with
+// RegisterProcessingTimeTimer.
+func (tc *TriggerContext) DeleteProcessingTimeTimer(timestamp uint64) error {
+	return tc.services.processingTime.DeleteTimer(tc.key, tc.window.namespace(), timestamp)
+}
+
+// Value returns the trigger value name for this key and window.
+func (tc *TriggerContext) Value(name string) (uint64, bool, error) {
+	state, err := tc.value(name)
+	if err != nil {
+		return 0, false, err
+	}
+	return state.Value()
+}
+
+// SetValue stores the trigger value name for this key and window.
+func (tc *TriggerContext) SetValue(name string, value uint64) error {
+	state, err := tc.value(name)
+	if err != nil {
+		return err
+	}
+	return state.Update(value)
+}
+
+// ClearValue removes the trigger value name for this key and window.
+func (tc *TriggerContext) ClearValue(name string) error {
+	state, err := tc.value(name)
+	if err != nil {
+		return err
+	}
+	return state.Clear()
+}
+
+func (tc *TriggerContext) value(name string) (*keyed.KeyedValueState[uint64], error) {
+	return tc.services.values.NewKeyedValue(tc.key, append(tc.window.namespace(), name...))
+}
+
+func openTriggerValues(ctx api.Context, storeName string) (*keyed.KeyedValueStateFactory[uint64], error) {
+	return keyed.NewKeyedValueStateFactoryFromContext[uint64](ctx, storeName, triggerKeyGroup, codec.Uint64Codec{})
+}
+
+type eventTimeTrigger struct{}
+
+// EventTimeTrigger fires a window once, when the watermark reaches its end.
+// It is the Operator's default.
+func EventTimeTrigger() Trigger {
+	return eventTimeTrigger{}
+}
+
+func (eventTimeTrigger) OnElement(tc *TriggerContext, _ uint64) (TriggerResult, error) {
+	return Continue, tc.RegisterEventTimeTimer(tc.window.MaxTimestamp())
+}
+
+func (eventTimeTrigger) OnEventTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	if timestamp == tc.window.MaxTimestamp() {
+		return Fire, nil
+	}
+	return Continue, nil
+}
+
+func (eventTimeTrigger) OnProcessingTime(*TriggerContext, uint64) (TriggerResult, error) {
+	return Continue, nil
+}
+
+func (eventTimeTrigger) OnMerge(tc *TriggerContext, _ []Window) error {
+	return tc.RegisterEventTimeTimer(tc.window.MaxTimestamp())
+}
+
+func (eventTimeTrigger) Clear(tc *TriggerContext) error {
+	return tc.DeleteEventTimeTimer(tc.window.MaxTimestamp())
+}
+
+const countValue = "count"
+
+type countTrigger struct {
+	count uint64
+}
+
+// CountTrigger fires a window every count records. It does not fire when
+// the window ends, so records after the last firing are not emitted; wrap it
+// with PurgingTrigger to emit each batch of count records once.
+func CountTrigger(count uint64) Trigger {
+	return countTrigger{count: max(count, 1)}
+}
+
+func (t countTrigger) OnElement(tc *TriggerContext, _ uint64) (TriggerResult, error) {
+	n, _, err := tc.Value(countValue)
+	if err != nil {
+		return Continue, err
+	}
+	if n+1 < t.count {
+		return Continue, tc.SetValue(countValue, n+1)
+	}
+	return Fire, tc.ClearValue(countValue)
+}
+
+func (countTrigger) OnEventTime(*TriggerContext, uint64) (TriggerResult, error) {
+	return Continue, nil
+}
+
+func (countTrigger) OnProcessingTime(*TriggerContext, uint64) (TriggerResult, error) {
+	return Continue, nil
+}
+
+func (countTrigger) OnMerge(tc *TriggerContext, merged []Window) error {
+	var total uint64
+	for _, w := range merged {
+		n, _, err := tc.ForWindow(w).Value(countValue)
+		if err != nil {
+			return err
+		}
+		total += n
+	}
+	return tc.SetValue(countValue, total)
+}
+
+func (countTrigger) Clear(tc *TriggerContext) error {
+	return tc.ClearValue(countValue)
+}
+
+const nextFireValue = "next-fire"
+
+// continuousTrigger fires at the window end and every interval in between,
+// in event or processing time. The next firing time is kept as a trigger
+// value so a restart does not lose it.
+type continuousTrigger struct {
+	interval  uint64
+	eventTime bool
+}
+
+// ContinuousEventTimeTrigger fires a window every interval of event time,
+// at watermarks aligned to multiples of interval, and when the window ends.
+func ContinuousEventTimeTrigger(interval time.Duration) Trigger {
+	return continuousTrigger{interval: max(millis(interval), 1), eventTime: true}
+}
+
+// ContinuousProcessingTimeTrigger fires a window every interval of
+// processing time while it is open, giving early results, and when the
+// watermark reaches its end. Processing-time firings happen only when the
+// Operator's FireProcessingTime runs.
+func ContinuousProcessingTimeTrigger(interval time.Duration) Trigger {
+	return continuousTrigger{interval: max(millis(interval), 1)}
+}
+
+func (t continuousTrigger) OnElement(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	if err := tc.RegisterEventTimeTimer(tc.window.MaxTimestamp()); err != nil {
+		return Continue, err
+	}
+	if _, found, err := tc.Value(nextFireValue); err != nil || found {
+		return Continue, err
+	}
+	if t.eventTime {
+		return Continue, t.schedule(tc, timestamp-timestamp%t.interval+t.interval)
+	}
+	return Continue, t.schedule(tc, tc.CurrentProcessingTime()+t.interval)
+}
+
+func (t continuousTrigger) OnEventTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	if timestamp == tc.window.MaxTimestamp() {
+		return Fire, nil
+	}
+	if !t.eventTime {
+		return Continue, nil
+	}
+	return t.onTimer(tc, timestamp)
+}
+
+func (t continuousTrigger) OnProcessingTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	if t.eventTime {
+		return Continue, nil
+	}
+	return t.onTimer(tc, timestamp)
+}
+
+func (t continuousTrigger) onTimer(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	next, found, err := tc.Value(nextFireValue)
+	if err != nil || !found || next != timestamp {
+		return Continue, err
+	}
+	return Fire, t.schedule(tc, timestamp+t.interval)
+}
+
+// schedule registers the next firing at timestamp. Event-time firings at or
+// past the window end are left to the end-of-window firing.
+func (t continuousTrigger) schedule(tc *TriggerContext, timestamp uint64) error {
+	if t.eventTime && timestamp >= tc.window.MaxTimestamp() {
+		return tc.ClearValue(nextFireValue)
+	}
+	if err := tc.SetValue(nextFireValue, timestamp); err != nil {
+		return err
+	}
+	if t.eventTime {
+		return tc.RegisterEventTimeTimer(timestamp)
+	}
+	return tc.RegisterProcessingTimeTimer(timestamp)
+}
+
+func (t continuousTrigger) OnMerge(tc *TriggerContext, merged []Window) error {
+	if err := tc.RegisterEventTimeTimer(tc.window.MaxTimestamp()); err != nil {
+		return err
+	}
+	var earliest uint64
+	found := false
+	for _, w := range merged {
+		next, ok, err := tc.ForWindow(w).Value(nextFireValue)
+		if err != nil {
+			return err
+		}
+		if ok && (!found || next < earliest) {
+			earliest, found = next, true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return t.schedule(tc, earliest)
+}
+
+func (t continuousTrigger) Clear(tc *TriggerContext) error {
+	if err := tc.DeleteEventTimeTimer(tc.window.MaxTimestamp()); err != nil {
+		return err
+	}
+	next, found, err := tc.Value(nextFireValue)
+	if err != nil || !found {
+		return err
+	}
+	if t.eventTime {
+		err = tc.DeleteEventTimeTimer(next)
+	} else {
+		err = tc.DeleteProcessingTimeTimer(next)
+	}
+	if err != nil {
+		return err
+	}
+	return tc.ClearValue(nextFireValue)
+}
+
+type purgingTrigger struct {
+	Trigger
+}
+
+// PurgingTrigger turns every Fire of trigger into FireAndPurge, so each
+// firing emits only what arrived since the previous one.
+func PurgingTrigger(trigger Trigger) Trigger {
+	return purgingTrigger{Trigger: trigger}
+}
+
+func purge(result TriggerResult, err error) (TriggerResult, error) {
+	if result == Fire {
+		result = FireAndPurge
+	}
+	return result, err
+}
+
+func (t purgingTrigger) OnElement(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	return purge(t.Trigger.OnElement(tc, timestamp))
+}
+
+func (t purgingTrigger) OnEventTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	return purge(t.Trigger.OnEventTime(tc, timestamp))
+}
+
+func (t purgingTrigger) OnProcessingTime(tc *TriggerContext, timestamp uint64) (TriggerResult, error) {
+	return purge(t.Trigger.OnProcessingTime(tc, timestamp))
+}
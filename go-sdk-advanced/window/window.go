@@ -0,0 +1,175 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package window groups keyed records into event-time windows. An Operator
+// assigns each record to tumbling, sliding or session windows by its event
+// timestamp, accumulates it in keyed state namespaced by the window, and
+// emits each window's result once the watermark passes the window's end, or
+// whenever a custom Trigger fires. Process windows keep every record and
+// accept an Evictor.
+package window
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/codec"
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Window is a half-open interval [Start, End) of event time in Unix
+// milliseconds.
+type Window struct {
+	Start uint64
+	End   uint64
+}
+
+// MaxTimestamp returns the last timestamp that belongs to the window.
+func (w Window) MaxTimestamp() uint64 {
+	return w.End - 1
+}
+
+// Intersects reports whether w and other share a timestamp or touch.
+func (w Window) Intersects(other Window) bool {
+	return w.Start <= other.End && other.Start <= w.End
+}
+
+// Cover returns the smallest window containing both w and other.
+func (w Window) Cover(other Window) Window {
+	return Window{Start: min(w.Start, other.Start), End: max(w.End, other.End)}
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("[%d, %d)", w.Start, w.End)
+}
+
+// windowCodec encodes a Window as its big-endian start and end; the encoding
+// is used as the state namespace of the window.
+type windowCodec struct{}
+
+var _ codec.Codec[Window] = windowCodec{}
+
+func (windowCodec) Encode(w Window) ([]byte, error) {
+	return w.namespace(), nil
+}
+
+func (windowCodec) Decode(data []byte) (Window, error) {
+	if len(data) != 16 {
+		return Window{}, fmt.Errorf("window must be 16 bytes, got %d", len(data))
+	}
+	return Window{Start: binary.BigEndian.Uint64(data), End: binary.BigEndian.Uint64(data[8:])}, nil
+}
+
+func (windowCodec) EncodedSize() int        { return 16 }
+func (windowCodec) IsOrderedKeyCodec() bool { return true }
+
+func (w Window) namespace() []byte {
+	out := make([]byte, 16)
+	binary.BigEndian.PutUint64(out, w.Start)
+	binary.BigEndian.PutUint64(out[8:], w.End)
+	return out
+}
+
+// Assigner assigns an event timestamp to the windows it belongs to. Merging
+// assigners produce windows that are merged with the key's existing windows
+// when they intersect, as session windows are.
+type Assigner interface {
+	AssignWindows(timestamp uint64) []Window
+	IsMerging() bool
+}
+
+type tumbling struct {
+	size uint64
+}
+
+// Tumbling returns an assigner of fixed-size, non-overlapping windows
+// aligned to the Unix epoch.
+func Tumbling(size time.Duration) Assigner {
+	return tumbling{size: millis(size)}
+}
+
+func (a tumbling) AssignWindows(timestamp uint64) []Window {
+	start := timestamp - timestamp%a.size
+	return []Window{{Start: start, End: start + a.size}}
+}
+
+func (tumbling) IsMerging() bool { return false }
+
+func (a tumbling) validate() error {
+	if a.size == 0 {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "tumbling window size must be at least 1ms")
+	}
+	return nil
+}
+
+type sliding struct {
+	size  uint64
+	slide uint64
+}
+
+// Sliding returns an assigner of windows of the given size that start every
+// slide, so a timestamp belongs to about size/slide windows.
+func Sliding(size, slide time.Duration) Assigner {
+	return sliding{size: millis(size), slide: millis(slide)}
+}
+
+func (a sliding) AssignWindows(timestamp uint64) []Window {
+	windows := make([]Window, 0, (a.size+a.slide-1)/a.slide)
+	for start := timestamp - timestamp%a.slide; start+a.size > timestamp; start -= a.slide {
+		windows = append(windows, Window{Start: start, End: start + a.size})
+		if start < a.slide {
+			break
+		}
+	}
+	return windows
+}
+
+func (sliding) IsMerging() bool { return false }
+
+func (a sliding) validate() error {
+	if a.size == 0 || a.slide == 0 {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "sliding window size and slide must be at least 1ms")
+	}
+	return nil
+}
+
+type session struct {
+	gap uint64
+}
+
+// Session returns an assigner of per-key sessions: a record opens the window
+// [timestamp, timestamp+gap), and windows that intersect are merged, so a
+// session closes after gap without records for its key.
+func Session(gap time.Duration) Assigner {
+	return session{gap: millis(gap)}
+}
+
+func (a session) AssignWindows(timestamp uint64) []Window {
+	return []Window{{Start: timestamp, End: timestamp + a.gap}}
+}
+
+func (session) IsMerging() bool { return true }
+
+func (a session) validate() error {
+	if a.gap == 0 {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "session window gap must be at least 1ms")
+	}
+	return nil
+}
+
+func millis(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64(d.Milliseconds())
+}
@@ -0,0 +1,131 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package window_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk-advanced/window"
+)
+
+func TestAssignWindows(t *testing.T) {
+	tests := []struct {
+		name      string
+		assigner  window.Assigner
+		timestamp uint64
+		want      []window.Window
+	}{
+		{
+			name:      "tumbling at zero",
+			assigner:  window.Tumbling(10 * time.Millisecond),
+			timestamp: 0,
+			want:      []window.Window{{Start: 0, End: 10}},
+		},
+		{
+			name:      "tumbling on a boundary",
+			assigner:  window.Tumbling(10 * time.Millisecond),
+			timestamp: 10,
+			want:      []window.Window{{Start: 10, End: 20}},
+		},
+		{
+			name:      "sliding at zero",
+			assigner:  window.Sliding(10*time.Millisecond, 5*time.Millisecond),
+			timestamp: 0,
+			want:      []window.Window{{Start: 0, End: 10}},
+		},
+		{
+			name:      "sliding below the first slide",
+			assigner:  window.Sliding(10*time.Millisecond, 5*time.Millisecond),
+			timestamp: 3,
+			want:      []window.Window{{Start: 0, End: 10}},
+		},
+		{
+			name:      "sliding inside the first window",
+			assigner:  window.Sliding(10*time.Millisecond, 5*time.Millisecond),
+			timestamp: 7,
+			want:      []window.Window{{Start: 5, End: 15}, {Start: 0, End: 10}},
+		},
+		{
+			name:      "sliding with an uneven slide",
+			assigner:  window.Sliding(10*time.Millisecond, 3*time.Millisecond),
+			timestamp: 4,
+			want:      []window.Window{{Start: 3, End: 13}, {Start: 0, End: 10}},
+		},
+		{
+			name:      "sliding away from zero",
+			assigner:  window.Sliding(10*time.Millisecond, 5*time.Millisecond),
+			timestamp: 12,
+			want:      []window.Window{{Start: 10, End: 20}, {Start: 5, End: 15}},
+		},
+		{
+			name:      "session opens one gap",
+			assigner:  window.Session(10 * time.Millisecond),
+			timestamp: 3,
+			want:      []window.Window{{Start: 3, End: 13}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.assigner.AssignWindows(tt.timestamp)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AssignWindows(%d) = %v, want %v", tt.timestamp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowIntersectsAndCover(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       window.Window
+		intersects bool
+		cover      window.Window
+	}{
+		{
+			name:       "overlapping",
+			a:          window.Window{Start: 0, End: 10},
+			b:          window.Window{Start: 5, End: 15},
+			intersects: true,
+			cover:      window.Window{Start: 0, End: 15},
+		},
+		{
+			name:       "touching",
+			a:          window.Window{Start: 0, End: 10},
+			b:          window.Window{Start: 10, End: 20},
+			intersects: true,
+			cover:      window.Window{Start: 0, End: 20},
+		},
+		{
+			name:       "apart",
+			a:          window.Window{Start: 0, End: 10},
+			b:          window.Window{Start: 11, End: 20},
+			intersects: false,
+			cover:      window.Window{Start: 0, End: 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Intersects(tt.b); got != tt.intersects {
+				t.Errorf("%v.Intersects(%v) = %v, want %v", tt.a, tt.b, got, tt.intersects)
+			}
+			if got := tt.b.Intersects(tt.a); got != tt.intersects {
+				t.Errorf("%v.Intersects(%v) = %v, want %v", tt.b, tt.a, got, tt.intersects)
+			}
+			if got := tt.a.Cover(tt.b); got != tt.cover {
+				t.Errorf("%v.Cover(%v) = %v, want %v", tt.a, tt.b, got, tt.cover)
+			}
+		})
+	}
+}
@@ -20,3 +20,19 @@ type Context interface {
 	Config() map[string]string
 	Close() error
 }
+
+// Flusher is implemented by contexts that buffer emits. Buffered emits are
+// flushed by the runtime at the end of every callback; drivers only need
+// Flush to push records out earlier.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush sends the emits ctx has buffered. It is a no-op for contexts that do
+// not buffer.
+func Flush(ctx Context) error {
+	if flusher, ok := ctx.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
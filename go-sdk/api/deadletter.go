@@ -0,0 +1,25 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+// DeadLetter is the JSON envelope the guest runtime emits on the dead-letter
+// target for a record whose Process call failed. Data is base64 in JSON.
+// Watermark is the latest watermark received on SourceID, if any.
+type DeadLetter struct {
+	SourceID     uint32    `json:"source_id"`
+	Data         []byte    `json:"data"`
+	ErrorCode    ErrorCode `json:"error_code"`
+	ErrorMessage string    `json:"error_message"`
+	Attempts     int       `json:"attempts"`
+	Watermark    *uint64   `json:"watermark,omitempty"`
+}
@@ -28,6 +28,27 @@ type Driver interface {
 	Custom(ctx Context, payload []byte) ([]byte, error)
 }
 
+// BatchProcessor is an optional Driver extension for processing consecutive
+// records from one source in a single call, e.g. to amortize state reads.
+// Drivers that do not implement it get each record through Process.
+type BatchProcessor interface {
+	ProcessBatch(ctx Context, sourceID uint32, records [][]byte) error
+}
+
+// ProcessBatch calls driver.ProcessBatch when the driver is a BatchProcessor,
+// and otherwise Process for each record in order, stopping at the first error.
+func ProcessBatch(driver Driver, ctx Context, sourceID uint32, records [][]byte) error {
+	if batcher, ok := driver.(BatchProcessor); ok {
+		return batcher.ProcessBatch(ctx, sourceID, records)
+	}
+	for _, record := range records {
+		if err := driver.Process(ctx, sourceID, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type BaseDriver struct{}
 
 func (BaseDriver) Init(Context, map[string]string) error {
@@ -12,7 +12,10 @@
 
 package api
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type ErrorCode string
 
@@ -25,8 +28,22 @@ const (
 	ErrStoreNotFound         ErrorCode = "store_not_found"
 	ErrStoreIO               ErrorCode = "store_io"
 	ErrResultUnexpected      ErrorCode = "result_unexpected"
+	// ErrDriver is reported to the host for driver errors that are not SDKErrors.
+	ErrDriver ErrorCode = "driver_error"
+	// ErrDriverPanic reports a panic recovered from a driver callback.
+	ErrDriverPanic ErrorCode = "driver_panic"
+	// ErrRuntimeInvalidConfig reports an invalid SDK option in the init config.
+	ErrRuntimeInvalidConfig ErrorCode = "runtime_invalid_config"
+	// ErrDecode reports an input record that its codec could not decode.
+	ErrDecode ErrorCode = "decode_error"
 )
 
+// Retryable reports whether a call failing with this code may succeed if
+// repeated unchanged. Only transient store IO failures are retryable.
+func (c ErrorCode) Retryable() bool {
+	return c == ErrStoreIO
+}
+
 type SDKError struct {
 	Code    ErrorCode
 	Message string
@@ -36,6 +53,18 @@ func (e *SDKError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Retryable reports whether the error's code is retryable.
+func (e *SDKError) Retryable() bool {
+	return e.Code.Retryable()
+}
+
+// IsRetryable reports whether err, or an error it wraps, has a
+// Retryable() bool method returning true.
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	return errors.As(err, &r) && r.Retryable()
+}
+
 // NewError builds an SDKError for use by implementations.
 func NewError(code ErrorCode, format string, args ...any) error {
 	return &SDKError{
@@ -0,0 +1,318 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bind fills a struct from a driver's init config using `fs` struct
+// tags, so drivers do not hand-parse map[string]string:
+//
+//	type Config struct {
+//		WindowSize time.Duration `fs:"window_size,default=60s,min=1s"`
+//		Topic      string        `fs:"topic,required,regex='^[a-z.]+$'"`
+//		Mode       string        `fs:"mode,default=fast,enum=fast|exact"`
+//		Targets    []uint32      `fs:"targets,sep=;"`
+//		Sink       SinkConfig    `fs:"sink"` // keys sink.<name>
+//	}
+//
+// Tag options are required, default=, min=, max=, enum= (values separated by
+// |), regex= and sep= (slice separator, default ","). Quote an option value
+// with single quotes when it contains commas. min and max bound numbers and
+// durations by value, and strings and slices by length. enum and regex apply
+// to strings and to each slice element. A missing key takes its default
+// before required is checked, so required only fails keys without one.
+package bind
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Config fills the struct target points to from values. Values are trimmed,
+// and an empty value counts as unset: the field gets its default, or keeps
+// its current value when it has none. Fields without an fs tag, or tagged
+// "-", are left alone. Every missing or invalid key is reported in one
+// ErrRuntimeInvalidConfig error. An unusable target or tag is reported as
+// ErrRuntimeInvalidDriver.
+func Config(values map[string]string, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "config target must be a non-nil pointer to a struct, got %T", target)
+	}
+	var problems []string
+	if err := bindStruct(values, v.Elem(), "", &problems); err != nil {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "config binding: %v", err)
+	}
+	if len(problems) > 0 {
+		return api.NewError(api.ErrRuntimeInvalidConfig, "invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func bindStruct(values map[string]string, v reflect.Value, prefix string, problems *[]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		rawTag, ok := field.Tag.Lookup("fs")
+		if !ok || rawTag == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			return fmt.Errorf("field %s.%s is tagged but not exported", t.Name(), field.Name)
+		}
+		tag, err := parseTag(rawTag)
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %v", t.Name(), field.Name, err)
+		}
+		if tag.name == "" {
+			return fmt.Errorf("field %s.%s: tag has no key name", t.Name(), field.Name)
+		}
+		key := prefix + tag.name
+		if isNested(field.Type) {
+			if err := bindStruct(values, v.Field(i), key+".", problems); err != nil {
+				return err
+			}
+			continue
+		}
+		if !supported(field.Type) {
+			return fmt.Errorf("field %s.%s: unsupported type %s", t.Name(), field.Name, field.Type)
+		}
+		problem, err := bindField(v.Field(i), tag, strings.TrimSpace(values[key]))
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %v", t.Name(), field.Name, err)
+		}
+		if problem != "" {
+			*problems = append(*problems, key+": "+problem)
+		}
+	}
+	return nil
+}
+
+// bindField sets and validates one field. It returns a problem with the
+// configured value, or an error for a bad tag.
+func bindField(field reflect.Value, tag fieldTag, raw string) (string, error) {
+	if raw == "" {
+		switch {
+		case tag.hasDefault:
+			raw = tag.defaultVal
+		case tag.required:
+			return "required", nil
+		default:
+			return "", nil
+		}
+	}
+	value, err := parseField(field.Type(), raw, tag.sep)
+	if err != nil {
+		return fmt.Sprintf("invalid value %q: %v", raw, err), nil
+	}
+	problem, err := validate(value, tag)
+	if problem != "" || err != nil {
+		return problem, err
+	}
+	field.Set(value)
+	return "", nil
+}
+
+func parseField(t reflect.Type, raw string, sep string) (reflect.Value, error) {
+	if t.Kind() != reflect.Slice {
+		return parseValue(t, raw)
+	}
+	var items []string
+	for _, item := range strings.Split(raw, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	out := reflect.MakeSlice(t, len(items), len(items))
+	for idx, item := range items {
+		value, err := parseValue(t.Elem(), item)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("item %d: %v", idx, err)
+		}
+		out.Index(idx).Set(value)
+	}
+	return out, nil
+}
+
+func parseValue(t reflect.Type, raw string) (reflect.Value, error) {
+	out := reflect.New(t).Elem()
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return out, out.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	if t == durationType {
+		d, err := time.ParseDuration(raw)
+		out.SetInt(int64(d))
+		return out, err
+	}
+	switch t.Kind() {
+	case reflect.String:
+		out.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return out, fmt.Errorf("not a bool")
+		}
+		out.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return out, numError(err)
+		}
+		out.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return out, numError(err)
+		}
+		out.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return out, numError(err)
+		}
+		out.SetFloat(f)
+	}
+	return out, nil
+}
+
+func numError(err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return numErr.Err
+	}
+	return err
+}
+
+func validate(value reflect.Value, tag fieldTag) (string, error) {
+	if value.Kind() == reflect.Slice {
+		for idx := 0; idx < value.Len(); idx++ {
+			if problem := checkPattern(value.Index(idx), tag); problem != "" {
+				return fmt.Sprintf("item %d: %s", idx, problem), nil
+			}
+		}
+	} else if problem := checkPattern(value, tag); problem != "" {
+		return problem, nil
+	}
+	if tag.min != "" {
+		if problem, err := checkBound(value, tag.min, -1); problem != "" || err != nil {
+			return problem, err
+		}
+	}
+	if tag.max != "" {
+		return checkBound(value, tag.max, 1)
+	}
+	return "", nil
+}
+
+// checkPattern applies enum and regex to a string value.
+func checkPattern(value reflect.Value, tag fieldTag) string {
+	if value.Kind() != reflect.String {
+		return ""
+	}
+	s := value.String()
+	if len(tag.enum) > 0 {
+		found := false
+		for _, allowed := range tag.enum {
+			found = found || s == allowed
+		}
+		if !found {
+			return fmt.Sprintf("%q is not one of %s", s, strings.Join(tag.enum, ", "))
+		}
+	}
+	if tag.pattern != nil && !tag.pattern.MatchString(s) {
+		return fmt.Sprintf("%q does not match %s", s, tag.pattern)
+	}
+	return ""
+}
+
+// checkBound compares value against a min (dir -1) or max (dir 1) bound.
+func checkBound(value reflect.Value, rawBound string, dir int) (string, error) {
+	name := map[int]string{-1: "min", 1: "max"}[dir]
+	var cmp int
+	switch value.Kind() {
+	case reflect.String, reflect.Slice:
+		bound, err := strconv.Atoi(rawBound)
+		if err != nil {
+			return "", fmt.Errorf("%s=%s: length bound must be an integer", name, rawBound)
+		}
+		if cmp = compare(value.Len(), bound); cmp == dir {
+			return fmt.Sprintf("length %d is %s %d", value.Len(), beyond(dir), bound), nil
+		}
+		return "", nil
+	case reflect.Bool:
+		return "", fmt.Errorf("%s does not apply to bool", name)
+	}
+	bound, err := parseValue(value.Type(), rawBound)
+	if err != nil {
+		return "", fmt.Errorf("%s=%s: %v", name, rawBound, err)
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		cmp = compare(value.Int(), bound.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		cmp = compare(value.Uint(), bound.Uint())
+	case reflect.Float32, reflect.Float64:
+		cmp = compare(value.Float(), bound.Float())
+	default:
+		return "", fmt.Errorf("%s does not apply to %s", name, value.Type())
+	}
+	if cmp == dir {
+		return fmt.Sprintf("%v is %s %s", value.Interface(), beyond(dir), rawBound), nil
+	}
+	return "", nil
+}
+
+func compare[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func beyond(dir int) string {
+	if dir < 0 {
+		return "below the minimum"
+	}
+	return "above the maximum"
+}
+
+// isNested reports whether t is a struct bound key by key under a prefix.
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func supported(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		elem := t.Elem()
+		return elem.Kind() != reflect.Slice && supported(elem)
+	}
+	return false
+}
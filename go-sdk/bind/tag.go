@@ -0,0 +1,95 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bind
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fieldTag is a parsed `fs:"..."` tag.
+type fieldTag struct {
+	name       string
+	required   bool
+	hasDefault bool
+	defaultVal string
+	min        string
+	max        string
+	enum       []string
+	pattern    *regexp.Regexp
+	sep        string
+}
+
+// parseTag parses `name,option,key=value,...`. A value may be wrapped in
+// single quotes to contain commas, e.g. default='a,b' or regex='^.{1,8}$'.
+func parseTag(tag string) (fieldTag, error) {
+	parts, err := splitTag(tag)
+	if err != nil {
+		return fieldTag{}, err
+	}
+	parsed := fieldTag{name: strings.TrimSpace(parts[0]), sep: ","}
+	for _, part := range parts[1:] {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "required":
+			parsed.required = true
+		case "default":
+			parsed.hasDefault, parsed.defaultVal = true, value
+		case "min":
+			parsed.min = value
+		case "max":
+			parsed.max = value
+		case "enum":
+			parsed.enum = strings.Split(value, "|")
+		case "regex":
+			pattern, err := regexp.Compile(value)
+			if err != nil {
+				return fieldTag{}, fmt.Errorf("regex: %v", err)
+			}
+			parsed.pattern = pattern
+		case "sep":
+			if value == "" {
+				return fieldTag{}, fmt.Errorf("sep must not be empty")
+			}
+			parsed.sep = value
+		default:
+			return fieldTag{}, fmt.Errorf("unknown option %q", key)
+		}
+		if key != "required" && !hasValue {
+			return fieldTag{}, fmt.Errorf("option %q needs a value", key)
+		}
+	}
+	return parsed, nil
+}
+
+func splitTag(tag string) ([]string, error) {
+	var parts []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range tag {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	return append(parts, current.String()), nil
+}
@@ -16,20 +16,25 @@ package fssdk
 
 import (
 	"github.com/functionstream/function-stream/go-sdk/api"
-	"github.com/functionstream/function-stream/go-sdk/impl"
+	"github.com/functionstream/function-stream/go-sdk/bind"
+	"github.com/functionstream/function-stream/go-sdk/intercept"
 )
 
 // Re-export API types and errors so existing code keeps using fssdk.*.
 type (
-	Context    = api.Context
-	Store      = api.Store
-	Iterator   = api.Iterator
-	Driver     = api.Driver
-	BaseDriver = api.BaseDriver
-	Module     = api.Module
-	ComplexKey = api.ComplexKey
-	ErrorCode  = api.ErrorCode
-	SDKError   = api.SDKError
+	Context        = api.Context
+	Flusher        = api.Flusher
+	Store          = api.Store
+	Iterator       = api.Iterator
+	Driver         = api.Driver
+	BaseDriver     = api.BaseDriver
+	BatchProcessor = api.BatchProcessor
+	Module         = api.Module
+	ComplexKey     = api.ComplexKey
+	ErrorCode      = api.ErrorCode
+	SDKError       = api.SDKError
+	DeadLetter     = api.DeadLetter
+	Interceptor    = intercept.Interceptor
 )
 
 // Re-export error codes.
@@ -42,9 +47,29 @@ const (
 	ErrStoreNotFound         = api.ErrStoreNotFound
 	ErrStoreIO               = api.ErrStoreIO
 	ErrResultUnexpected      = api.ErrResultUnexpected
+	ErrDriver                = api.ErrDriver
+	ErrDriverPanic           = api.ErrDriverPanic
+	ErrRuntimeInvalidConfig  = api.ErrRuntimeInvalidConfig
+	ErrDecode                = api.ErrDecode
 )
 
-// Run wires the driver to the WASM processor. Call from main.
-func Run(driver Driver) {
-	impl.Run(driver)
+// IsRetryable reports whether err is a transient failure; see api.IsRetryable.
+func IsRetryable(err error) bool {
+	return api.IsRetryable(err)
+}
+
+// Flush sends the emits ctx has buffered; see api.Flush.
+func Flush(ctx Context) error {
+	return api.Flush(ctx)
+}
+
+// Chain wraps driver with interceptors; see intercept.Chain.
+func Chain(driver Driver, interceptors ...Interceptor) Driver {
+	return intercept.Chain(driver, interceptors...)
+}
+
+// BindConfig fills the struct target points to from the init config using
+// `fs` struct tags; see bind.Config.
+func BindConfig(config map[string]string, target any) error {
+	return bind.Config(config, target)
 }
@@ -0,0 +1,70 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstest
+
+import (
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/impl"
+	"github.com/functionstream/function-stream/go-sdk/state/common"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+)
+
+// Backend is an impl.HostBackend that records every emit in call order and
+// opens stores from an in-memory backend.
+type Backend struct {
+	stores *memory.Backend
+	emits  []Emit
+}
+
+var _ impl.HostBackend = (*Backend)(nil)
+
+// NewBackend creates a recording backend whose stores live in stores; nil
+// creates a fresh in-memory backend.
+func NewBackend(stores *memory.Backend) *Backend {
+	if stores == nil {
+		stores = memory.NewBackend()
+	}
+	return &Backend{stores: stores}
+}
+
+func (b *Backend) Emit(targetID uint32, data []byte) error {
+	b.emits = append(b.emits, Emit{Kind: EmitData, TargetID: targetID, Data: common.DupBytes(data)})
+	return nil
+}
+
+func (b *Backend) EmitWatermark(targetID uint32, watermark uint64) error {
+	b.emits = append(b.emits, Emit{Kind: EmitWatermark, TargetID: targetID, Watermark: watermark})
+	return nil
+}
+
+func (b *Backend) OpenStore(name string) (api.Store, error) {
+	return b.stores.OpenStore(name), nil
+}
+
+// Emits returns every recorded emit in call order.
+func (b *Backend) Emits() []Emit {
+	return b.emitsSince(0)
+}
+
+// Stores returns the in-memory backend serving OpenStore.
+func (b *Backend) Stores() *memory.Backend {
+	return b.stores
+}
+
+func (b *Backend) emitsSince(mark int) []Emit {
+	out := make([]Emit, 0, len(b.emits)-mark)
+	for _, e := range b.emits[mark:] {
+		out = append(out, cloneEmit(e))
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fstest provides test doubles for running drivers under plain
+// `go test`, without TinyGo or a WASM host.
+package fstest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/state/common"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+)
+
+// EmitKind tells data emits and watermark emits apart.
+type EmitKind int
+
+const (
+	EmitData EmitKind = iota
+	EmitWatermark
+)
+
+func (k EmitKind) String() string {
+	switch k {
+	case EmitData:
+		return "data"
+	case EmitWatermark:
+		return "watermark"
+	default:
+		return "unknown"
+	}
+}
+
+// Emit is one recorded Context.Emit or Context.EmitWatermark call.
+type Emit struct {
+	Kind      EmitKind
+	TargetID  uint32
+	Data      []byte
+	Watermark uint64
+}
+
+// Context is an api.Context that records every emit and serves stores from an
+// in-memory backend. It follows the runtime context semantics: store names are
+// trimmed, an empty name fails with ErrStoreInvalidName and calls after Close
+// fail with ErrRuntimeClosed.
+type Context struct {
+	config     map[string]string
+	backend    *memory.Backend
+	stores     map[string]*memory.Store
+	storeNames []string
+	emits      []Emit
+	closed     bool
+}
+
+var _ api.Context = (*Context)(nil)
+
+// NewContext creates a recording context with its own in-memory backend.
+func NewContext(config map[string]string) *Context {
+	return NewContextWithBackend(memory.NewBackend(), config)
+}
+
+// NewContextWithBackend creates a recording context whose stores live in
+// backend, so state survives across contexts sharing it.
+func NewContextWithBackend(backend *memory.Backend, config map[string]string) *Context {
+	if backend == nil {
+		backend = memory.NewBackend()
+	}
+	return &Context{
+		config:  cloneStringMap(config),
+		backend: backend,
+		stores:  make(map[string]*memory.Store),
+	}
+}
+
+func (c *Context) Emit(targetID uint32, data []byte) error {
+	if c.closed {
+		return api.NewError(api.ErrRuntimeClosed, "emit on closed context")
+	}
+	c.emits = append(c.emits, Emit{Kind: EmitData, TargetID: targetID, Data: common.DupBytes(data)})
+	return nil
+}
+
+func (c *Context) EmitWatermark(targetID uint32, watermark uint64) error {
+	if c.closed {
+		return api.NewError(api.ErrRuntimeClosed, "emit watermark on closed context")
+	}
+	c.emits = append(c.emits, Emit{Kind: EmitWatermark, TargetID: targetID, Watermark: watermark})
+	return nil
+}
+
+func (c *Context) GetOrCreateStore(name string) (api.Store, error) {
+	storeName := strings.TrimSpace(name)
+	if storeName == "" {
+		return nil, api.NewError(api.ErrStoreInvalidName, "store name must not be empty")
+	}
+
+	if c.closed {
+		return nil, api.NewError(api.ErrRuntimeClosed, "store request on closed context")
+	}
+	if existing, ok := c.stores[storeName]; ok {
+		return existing, nil
+	}
+
+	store := c.backend.OpenStore(storeName)
+	c.stores[storeName] = store
+	c.storeNames = append(c.storeNames, storeName)
+	return store, nil
+}
+
+func (c *Context) Config() map[string]string {
+	return cloneStringMap(c.config)
+}
+
+func (c *Context) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	stores := c.stores
+	c.stores = make(map[string]*memory.Store)
+
+	var firstErr error
+	for _, store := range stores {
+		if err := store.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Closed reports whether Close has been called.
+func (c *Context) Closed() bool {
+	return c.closed
+}
+
+// Emits returns every recorded emit in call order.
+func (c *Context) Emits() []Emit {
+	out := make([]Emit, len(c.emits))
+	for idx, e := range c.emits {
+		out[idx] = cloneEmit(e)
+	}
+	return out
+}
+
+// EmitsByTarget returns the recorded emits grouped by target ID, each group in call order.
+func (c *Context) EmitsByTarget() map[uint32][]Emit {
+	out := make(map[uint32][]Emit)
+	for _, e := range c.emits {
+		out[e.TargetID] = append(out[e.TargetID], cloneEmit(e))
+	}
+	return out
+}
+
+// Targets returns the target IDs that received at least one emit, sorted.
+func (c *Context) Targets() []uint32 {
+	seen := make(map[uint32]struct{})
+	out := make([]uint32, 0)
+	for _, e := range c.emits {
+		if _, ok := seen[e.TargetID]; ok {
+			continue
+		}
+		seen[e.TargetID] = struct{}{}
+		out = append(out, e.TargetID)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Outputs returns the data emitted to targetID, in call order.
+func (c *Context) Outputs(targetID uint32) [][]byte {
+	out := make([][]byte, 0)
+	for _, e := range c.emits {
+		if e.Kind == EmitData && e.TargetID == targetID {
+			out = append(out, common.DupBytes(e.Data))
+		}
+	}
+	return out
+}
+
+// Watermarks returns the watermarks emitted to targetID, in call order.
+func (c *Context) Watermarks(targetID uint32) []uint64 {
+	out := make([]uint64, 0)
+	for _, e := range c.emits {
+		if e.Kind == EmitWatermark && e.TargetID == targetID {
+			out = append(out, e.Watermark)
+		}
+	}
+	return out
+}
+
+// Reset drops the recorded emits; stores and their contents are kept.
+func (c *Context) Reset() {
+	c.emits = nil
+}
+
+// StoreNames returns the trimmed store names requested through GetOrCreateStore, in first-use order.
+func (c *Context) StoreNames() []string {
+	out := make([]string, len(c.storeNames))
+	copy(out, c.storeNames)
+	return out
+}
+
+// Store returns the store opened under name, or nil if the driver never requested it.
+func (c *Context) Store(name string) *memory.Store {
+	return c.stores[strings.TrimSpace(name)]
+}
+
+// Backend returns the in-memory backend holding this context's stores.
+func (c *Context) Backend() *memory.Backend {
+	return c.backend
+}
+
+func cloneEmit(e Emit) Emit {
+	e.Data = common.DupBytes(e.Data)
+	return e
+}
+
+func cloneStringMap(input map[string]string) map[string]string {
+	out := make(map[string]string, len(input))
+	for k, v := range input {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,406 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstest
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Op names a wrapped method that faults can target.
+type Op string
+
+const (
+	OpPutState     Op = "Store.PutState"
+	OpGetState     Op = "Store.GetState"
+	OpDeleteState  Op = "Store.DeleteState"
+	OpListStates   Op = "Store.ListStates"
+	OpPut          Op = "Store.Put"
+	OpGet          Op = "Store.Get"
+	OpDelete       Op = "Store.Delete"
+	OpMerge        Op = "Store.Merge"
+	OpDeletePrefix Op = "Store.DeletePrefix"
+	OpListComplex  Op = "Store.ListComplex"
+	OpScanComplex  Op = "Store.ScanComplex"
+	OpStoreClose   Op = "Store.Close"
+
+	OpIteratorHasNext Op = "Iterator.HasNext"
+	OpIteratorNext    Op = "Iterator.Next"
+	OpIteratorClose   Op = "Iterator.Close"
+
+	OpEmit             Op = "Context.Emit"
+	OpEmitWatermark    Op = "Context.EmitWatermark"
+	OpGetOrCreateStore Op = "Context.GetOrCreateStore"
+	OpContextClose     Op = "Context.Close"
+	OpContextFlush     Op = "Context.Flush"
+)
+
+// FaultRule describes when a call to Op misbehaves. A rule fires on the
+// OnCall-th call of Op (1-based) or, when OnCall is 0, on each call with the
+// given Probability. A firing rule either fails the call with Code before it
+// reaches the wrapped value, or, when Code is empty, lets the call through and
+// keeps only the first Truncate bytes of the value returned by GetState, Get
+// or Iterator.Next.
+type FaultRule struct {
+	Op          Op
+	Code        api.ErrorCode
+	Truncate    int
+	OnCall      int
+	Probability float64
+}
+
+// FailOnCall fails the n-th call of op with code.
+func FailOnCall(op Op, n int, code api.ErrorCode) FaultRule {
+	return FaultRule{Op: op, Code: code, OnCall: n}
+}
+
+// FailWithProbability fails each call of op with code with probability p.
+func FailWithProbability(op Op, p float64, code api.ErrorCode) FaultRule {
+	return FaultRule{Op: op, Code: code, Probability: p}
+}
+
+// TruncateOnCall cuts the value returned by the n-th call of op to keep bytes.
+func TruncateOnCall(op Op, n int, keep int) FaultRule {
+	return FaultRule{Op: op, Truncate: keep, OnCall: n}
+}
+
+// TruncateWithProbability cuts the value returned by each call of op to keep
+// bytes with probability p.
+func TruncateWithProbability(op Op, p float64, keep int) FaultRule {
+	return FaultRule{Op: op, Truncate: keep, Probability: p}
+}
+
+// InjectedFault records a rule that fired.
+type InjectedFault struct {
+	Op   Op
+	Call int
+	Rule FaultRule
+}
+
+// FaultInjector counts calls per Op and decides which ones misbehave. Share
+// one injector between a context and its stores so call counts cover the
+// whole test.
+type FaultInjector struct {
+	mu       sync.Mutex
+	rand     *rand.Rand
+	rules    []FaultRule
+	calls    map[Op]int
+	injected []InjectedFault
+}
+
+// NewFaultInjector creates an injector; seed makes probabilistic rules
+// reproducible. Rules are checked in order and the first one that fires wins.
+func NewFaultInjector(seed int64, rules ...FaultRule) *FaultInjector {
+	return &FaultInjector{
+		rand:  rand.New(rand.NewSource(seed)),
+		rules: append([]FaultRule(nil), rules...),
+		calls: make(map[Op]int),
+	}
+}
+
+// Add appends rules to the injector.
+func (f *FaultInjector) Add(rules ...FaultRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rules...)
+}
+
+// Calls returns how many times op has been called.
+func (f *FaultInjector) Calls(op Op) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// Injected returns the faults fired so far, in order.
+func (f *FaultInjector) Injected() []InjectedFault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]InjectedFault(nil), f.injected...)
+}
+
+// Reset clears call counts and the injected log; rules are kept.
+func (f *FaultInjector) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = make(map[Op]int)
+	f.injected = nil
+}
+
+func (f *FaultInjector) next(op Op) (FaultRule, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[op]++
+	call := f.calls[op]
+	for _, rule := range f.rules {
+		if rule.Op != op {
+			continue
+		}
+		fired := false
+		if rule.OnCall > 0 {
+			fired = call == rule.OnCall
+		} else if rule.Probability > 0 {
+			fired = f.rand.Float64() < rule.Probability
+		}
+		if fired {
+			f.injected = append(f.injected, InjectedFault{Op: op, Call: call, Rule: rule})
+			return rule, true
+		}
+	}
+	return FaultRule{}, false
+}
+
+// check returns the injected error for op, if any, and the rule that fired.
+func (f *FaultInjector) check(op Op) (FaultRule, bool, error) {
+	rule, fired := f.next(op)
+	if !fired {
+		return FaultRule{}, false, nil
+	}
+	if rule.Code != "" {
+		return rule, true, api.NewError(rule.Code, "injected fault on %s", op)
+	}
+	return rule, true, nil
+}
+
+func (f *FaultInjector) fail(op Op) error {
+	_, _, err := f.check(op)
+	return err
+}
+
+func truncate(rule FaultRule, fired bool, value []byte) []byte {
+	if !fired || rule.Code != "" || rule.Truncate < 0 || rule.Truncate >= len(value) {
+		return value
+	}
+	return value[:rule.Truncate]
+}
+
+// FaultyContext wraps an api.Context; stores it returns are FaultyStores
+// sharing the same injector.
+type FaultyContext struct {
+	inner    api.Context
+	injector *FaultInjector
+}
+
+var _ api.Context = (*FaultyContext)(nil)
+
+// NewFaultyContext wraps inner with faults from injector.
+func NewFaultyContext(inner api.Context, injector *FaultInjector) *FaultyContext {
+	return &FaultyContext{inner: inner, injector: injector}
+}
+
+// Injector returns the injector shared by the context and its stores.
+func (c *FaultyContext) Injector() *FaultInjector {
+	return c.injector
+}
+
+func (c *FaultyContext) Emit(targetID uint32, data []byte) error {
+	if err := c.injector.fail(OpEmit); err != nil {
+		return err
+	}
+	return c.inner.Emit(targetID, data)
+}
+
+func (c *FaultyContext) EmitWatermark(targetID uint32, watermark uint64) error {
+	if err := c.injector.fail(OpEmitWatermark); err != nil {
+		return err
+	}
+	return c.inner.EmitWatermark(targetID, watermark)
+}
+
+// Flush forwards to the wrapped context; see api.Flush.
+func (c *FaultyContext) Flush() error {
+	if err := c.injector.fail(OpContextFlush); err != nil {
+		return err
+	}
+	return api.Flush(c.inner)
+}
+
+func (c *FaultyContext) GetOrCreateStore(name string) (api.Store, error) {
+	if err := c.injector.fail(OpGetOrCreateStore); err != nil {
+		return nil, err
+	}
+	store, err := c.inner.GetOrCreateStore(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewFaultyStore(store, c.injector), nil
+}
+
+func (c *FaultyContext) Config() map[string]string {
+	return c.inner.Config()
+}
+
+// Close closes the wrapped context even when a fault is injected, as a real
+// failed close still releases it; the injected error replaces the result.
+func (c *FaultyContext) Close() error {
+	err := c.inner.Close()
+	if injected := c.injector.fail(OpContextClose); injected != nil {
+		return injected
+	}
+	return err
+}
+
+// FaultyStore wraps an api.Store; iterators it returns are wrapped too.
+type FaultyStore struct {
+	inner    api.Store
+	injector *FaultInjector
+}
+
+var _ api.Store = (*FaultyStore)(nil)
+
+// NewFaultyStore wraps inner with faults from injector.
+func NewFaultyStore(inner api.Store, injector *FaultInjector) *FaultyStore {
+	return &FaultyStore{inner: inner, injector: injector}
+}
+
+func (s *FaultyStore) PutState(key []byte, value []byte) error {
+	if err := s.injector.fail(OpPutState); err != nil {
+		return err
+	}
+	return s.inner.PutState(key, value)
+}
+
+func (s *FaultyStore) GetState(key []byte) ([]byte, bool, error) {
+	rule, fired, err := s.injector.check(OpGetState)
+	if err != nil {
+		return nil, false, err
+	}
+	value, found, err := s.inner.GetState(key)
+	return truncate(rule, fired, value), found, err
+}
+
+func (s *FaultyStore) DeleteState(key []byte) error {
+	if err := s.injector.fail(OpDeleteState); err != nil {
+		return err
+	}
+	return s.inner.DeleteState(key)
+}
+
+func (s *FaultyStore) ListStates(startInclusive []byte, endExclusive []byte) ([][]byte, error) {
+	if err := s.injector.fail(OpListStates); err != nil {
+		return nil, err
+	}
+	return s.inner.ListStates(startInclusive, endExclusive)
+}
+
+func (s *FaultyStore) Put(key api.ComplexKey, value []byte) error {
+	if err := s.injector.fail(OpPut); err != nil {
+		return err
+	}
+	return s.inner.Put(key, value)
+}
+
+func (s *FaultyStore) Get(key api.ComplexKey) ([]byte, bool, error) {
+	rule, fired, err := s.injector.check(OpGet)
+	if err != nil {
+		return nil, false, err
+	}
+	value, found, err := s.inner.Get(key)
+	return truncate(rule, fired, value), found, err
+}
+
+func (s *FaultyStore) Delete(key api.ComplexKey) error {
+	if err := s.injector.fail(OpDelete); err != nil {
+		return err
+	}
+	return s.inner.Delete(key)
+}
+
+func (s *FaultyStore) Merge(key api.ComplexKey, value []byte) error {
+	if err := s.injector.fail(OpMerge); err != nil {
+		return err
+	}
+	return s.inner.Merge(key, value)
+}
+
+func (s *FaultyStore) DeletePrefix(key api.ComplexKey) error {
+	if err := s.injector.fail(OpDeletePrefix); err != nil {
+		return err
+	}
+	return s.inner.DeletePrefix(key)
+}
+
+func (s *FaultyStore) ListComplex(
+	keyGroup []byte,
+	key []byte,
+	namespace []byte,
+	startInclusive []byte,
+	endExclusive []byte,
+) ([][]byte, error) {
+	if err := s.injector.fail(OpListComplex); err != nil {
+		return nil, err
+	}
+	return s.inner.ListComplex(keyGroup, key, namespace, startInclusive, endExclusive)
+}
+
+func (s *FaultyStore) ScanComplex(keyGroup []byte, key []byte, namespace []byte) (api.Iterator, error) {
+	if err := s.injector.fail(OpScanComplex); err != nil {
+		return nil, err
+	}
+	it, err := s.inner.ScanComplex(keyGroup, key, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return NewFaultyIterator(it, s.injector), nil
+}
+
+// Close closes the wrapped store even when a fault is injected, as a real
+// failed close still releases it; the injected error replaces the result.
+func (s *FaultyStore) Close() error {
+	err := s.inner.Close()
+	if injected := s.injector.fail(OpStoreClose); injected != nil {
+		return injected
+	}
+	return err
+}
+
+// FaultyIterator wraps an api.Iterator.
+type FaultyIterator struct {
+	inner    api.Iterator
+	injector *FaultInjector
+}
+
+var _ api.Iterator = (*FaultyIterator)(nil)
+
+// NewFaultyIterator wraps inner with faults from injector.
+func NewFaultyIterator(inner api.Iterator, injector *FaultInjector) *FaultyIterator {
+	return &FaultyIterator{inner: inner, injector: injector}
+}
+
+func (i *FaultyIterator) HasNext() (bool, error) {
+	if err := i.injector.fail(OpIteratorHasNext); err != nil {
+		return false, err
+	}
+	return i.inner.HasNext()
+}
+
+func (i *FaultyIterator) Next() ([]byte, []byte, bool, error) {
+	rule, fired, err := i.injector.check(OpIteratorNext)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	key, value, ok, err := i.inner.Next()
+	return key, truncate(rule, fired, value), ok, err
+}
+
+// Close closes the wrapped iterator even when a fault is injected, as a real
+// failed close still releases it; the injected error replaces the result.
+func (i *FaultyIterator) Close() error {
+	err := i.inner.Close()
+	if injected := i.injector.fail(OpIteratorClose); injected != nil {
+		return injected
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+var updateGolden = flag.Bool("fstest.update", false, "rewrite fstest golden files with the current output")
+
+// RunScenario replays scenario against driver on a fresh harness and fails the
+// test with the step index if a step panics or its error outcome is unexpected.
+func RunScenario(t testing.TB, driver api.Driver, scenario Scenario) Transcript {
+	t.Helper()
+	transcript, err := NewHarness(driver).Run(scenario)
+	if err != nil {
+		t.Fatalf("fstest scenario failed: %v", err)
+	}
+	return transcript
+}
+
+// RunScenarioFile loads a scenario script (see LoadScenario) and runs it.
+func RunScenarioFile(t testing.TB, driver api.Driver, path string) Transcript {
+	t.Helper()
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		t.Fatalf("fstest load scenario %s: %v", path, err)
+	}
+	return RunScenario(t, driver, scenario)
+}
+
+// AssertGolden compares the transcript text with the golden file at path.
+// Run the test with -fstest.update to (re)write the file.
+func AssertGolden(t testing.TB, transcript Transcript, path string) {
+	t.Helper()
+	got := transcript.String()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("fstest golden mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("fstest golden write: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fstest golden read %s: %v (run with -fstest.update to create it)", path, err)
+	}
+	if string(want) != got {
+		t.Errorf("fstest golden mismatch for %s\n--- want\n%s--- got\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,251 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstest
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/impl"
+	"github.com/functionstream/function-stream/go-sdk/state/common"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+)
+
+// Harness drives an api.Driver through impl.Runtime, the same lifecycle code
+// the guest runtime uses: fs-init swaps in a fresh context (closing the
+// previous one), other exports reuse the current context, and fs-close closes
+// it. Host imports are served by a recording backend whose stores live in one
+// in-memory backend for the lifetime of the harness.
+type Harness struct {
+	driver  api.Driver
+	backend *Backend
+	rt      *impl.Runtime
+}
+
+// StepResult is the observable outcome of one step.
+type StepResult struct {
+	Index     int
+	Step      Step
+	Emits     []Emit
+	Err       error
+	Heartbeat bool
+	Response  []byte
+}
+
+// Transcript is the ordered list of step results produced by Harness.Run.
+type Transcript []StepResult
+
+// StepError reports the step at which a scenario failed.
+type StepError struct {
+	Index int
+	Step  Step
+	Err   error
+	// Panic holds the recovered value when the step panicked outside a
+	// driver callback. Driver panics are reported in Err as ErrDriverPanic.
+	Panic any
+	Stack []byte
+}
+
+func (e *StepError) Error() string {
+	if e.Panic != nil {
+		return fmt.Sprintf("step %d (%s): driver panicked: %v\n%s", e.Index, e.Step.Kind, e.Panic, e.Stack)
+	}
+	return fmt.Sprintf("step %d (%s): %v", e.Index, e.Step.Kind, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// NewHarness creates a harness for driver with an empty in-memory backend.
+func NewHarness(driver api.Driver) *Harness {
+	return NewHarnessWithBackend(driver, memory.NewBackend())
+}
+
+// NewHarnessWithBackend creates a harness whose stores live in stores.
+func NewHarnessWithBackend(driver api.Driver, stores *memory.Backend) *Harness {
+	h := &Harness{driver: driver, backend: NewBackend(stores)}
+	if driver != nil {
+		h.rt, _ = impl.NewRuntime(driver, h.backend)
+	}
+	return h
+}
+
+// Backend returns the recording host backend behind the harness.
+func (h *Harness) Backend() *Backend {
+	return h.backend
+}
+
+// Skipped returns how many records the skip failure_policy has dropped so
+// far.
+func (h *Harness) Skipped() uint64 {
+	if h.rt == nil {
+		return 0
+	}
+	return h.rt.Skipped()
+}
+
+// Stores returns the in-memory backend holding the driver's stores.
+func (h *Harness) Stores() *memory.Backend {
+	return h.backend.Stores()
+}
+
+// Run executes the scenario in order and stops at the first failing step. A
+// step fails when its error outcome does not match Step.ExpectError; a driver
+// panic is an ErrDriverPanic error, subject to the init failure_policy. The
+// transcript includes every step executed so far.
+func (h *Harness) Run(scenario Scenario) (Transcript, error) {
+	if h.rt == nil {
+		return nil, api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil")
+	}
+	out := make(Transcript, 0, len(scenario))
+	for idx, step := range scenario {
+		result, stepErr := h.Step(idx, step)
+		out = append(out, result)
+		if stepErr != nil {
+			return out, stepErr
+		}
+	}
+	return out, nil
+}
+
+// Step executes a single step; idx is only used for reporting.
+func (h *Harness) Step(idx int, step Step) (result StepResult, stepErr error) {
+	result = StepResult{Index: idx, Step: step}
+	if h.rt == nil {
+		return result, api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil")
+	}
+	mark := len(h.backend.emits)
+
+	defer func() {
+		result.Emits = h.backend.emitsSince(mark)
+		if recovered := recover(); recovered != nil {
+			stepErr = &StepError{Index: idx, Step: step, Panic: recovered, Stack: debug.Stack()}
+			result.Err = stepErr
+		}
+	}()
+
+	result.Err = h.invoke(step, &result)
+	switch {
+	case result.Err != nil && !step.ExpectError:
+		return result, &StepError{Index: idx, Step: step, Err: result.Err}
+	case result.Err == nil && step.ExpectError:
+		return result, &StepError{Index: idx, Step: step, Err: fmt.Errorf("expected an error, got none")}
+	}
+	return result, nil
+}
+
+func (h *Harness) invoke(step Step, result *StepResult) error {
+	switch step.Kind {
+	case StepInit:
+		return h.rt.Init(step.Config)
+	case StepProcess:
+		return h.rt.Process(step.SourceID, common.DupBytes(step.Data))
+	case StepProcessBatch:
+		records := make([][]byte, len(step.Records))
+		for idx, record := range step.Records {
+			records[idx] = common.DupBytes(record)
+		}
+		return h.rt.ProcessBatch(step.SourceID, records)
+	case StepWatermark:
+		return h.rt.ProcessWatermark(step.SourceID, step.Watermark)
+	case StepCheckpoint:
+		return h.rt.TakeCheckpoint(step.CheckpointID)
+	case StepHeartbeat:
+		result.Heartbeat = h.rt.CheckHeartbeat()
+		return nil
+	case StepCustom:
+		response, err := h.rt.Custom(common.DupBytes(step.Data))
+		result.Response = common.DupBytes(response)
+		return err
+	case StepExec:
+		return h.rt.Exec(step.ClassName, step.Modules)
+	case StepClose:
+		return h.rt.Close()
+	default:
+		return fmt.Errorf("unknown step kind %d", step.Kind)
+	}
+}
+
+// Emits returns every emit in the transcript, in order.
+func (t Transcript) Emits() []Emit {
+	out := make([]Emit, 0)
+	for _, result := range t {
+		out = append(out, result.Emits...)
+	}
+	return out
+}
+
+// Outputs returns the data emitted to targetID across the transcript.
+func (t Transcript) Outputs(targetID uint32) [][]byte {
+	out := make([][]byte, 0)
+	for _, e := range t.Emits() {
+		if e.Kind == EmitData && e.TargetID == targetID {
+			out = append(out, e.Data)
+		}
+	}
+	return out
+}
+
+// String renders the transcript in the stable text form used by golden files.
+func (t Transcript) String() string {
+	var b strings.Builder
+	for _, result := range t {
+		fmt.Fprintf(&b, "#%d %s\n", result.Index, result.Step)
+		for _, e := range result.Emits {
+			switch e.Kind {
+			case EmitData:
+				fmt.Fprintf(&b, "  emit %d %s\n", e.TargetID, strconv.Quote(string(e.Data)))
+			case EmitWatermark:
+				fmt.Fprintf(&b, "  watermark %d %d\n", e.TargetID, e.Watermark)
+			}
+		}
+		switch result.Step.Kind {
+		case StepHeartbeat:
+			fmt.Fprintf(&b, "  -> %t\n", result.Heartbeat)
+		case StepCustom:
+			fmt.Fprintf(&b, "  -> %s\n", strconv.Quote(string(result.Response)))
+		}
+		if result.Err != nil {
+			fmt.Fprintf(&b, "  error %s\n", strconv.Quote(errorSummary(result.Err)))
+		}
+	}
+	return b.String()
+}
+
+// errorSummary drops panic stacks so golden files stay stable.
+func errorSummary(err error) string {
+	if stepErr, ok := err.(*StepError); ok && stepErr.Panic != nil {
+		return fmt.Sprintf("panic: %v", stepErr.Panic)
+	}
+	var sdkErr *api.SDKError
+	if errors.As(err, &sdkErr) && sdkErr.Code == api.ErrDriverPanic {
+		message, _, _ := strings.Cut(err.Error(), "\n")
+		return message
+	}
+	return err.Error()
+}
+
+func sortedKeys(input map[string]string) []string {
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,222 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+)
+
+// RecoveryOptions configures CheckRecovery.
+type RecoveryOptions struct {
+	// Trials is the number of random (checkpoint, crash point) pairs to try;
+	// 0 tries every pair.
+	Trials int
+	// Seed picks the random pairs.
+	Seed int64
+}
+
+// RecoveryFailure reports a crash point after which the recovered run did not
+// match the uninterrupted one.
+type RecoveryFailure struct {
+	// CheckpointStep is the index of the checkpoint restored from.
+	CheckpointStep int
+	// CrashStep is the index of the first step lost in the crash.
+	CrashStep int
+	// Diff lists the differing outputs or store entries.
+	Diff []string
+}
+
+func (f *RecoveryFailure) Error() string {
+	return fmt.Sprintf("recovery from checkpoint at step %d after a crash before step %d diverged:\n  %s",
+		f.CheckpointStep, f.CrashStep, strings.Join(f.Diff, "\n  "))
+}
+
+// CheckRecovery verifies that driver state survives a crash. For each trial it
+// runs the scenario on a fresh driver, snapshots every store when a checkpoint
+// step succeeds, and crashes before a later step by dropping the driver
+// without Close. It then restores the snapshot, runs Init on a new driver with
+// the last init config, and replays the steps after the checkpoint. Outputs
+// emitted up to the checkpoint plus those of the replay, and the final store
+// contents, must equal an uninterrupted run. newDriver must return a fresh
+// driver on every call, the way a restarted process would.
+func CheckRecovery(newDriver func() api.Driver, scenario Scenario, opts RecoveryOptions) error {
+	want, err := runReference(newDriver, scenario)
+	if err != nil {
+		return err
+	}
+	pairs := crashPairs(scenario)
+	if len(pairs) == 0 {
+		return fmt.Errorf("scenario has no successful checkpoint step followed by other steps")
+	}
+	if opts.Trials > 0 {
+		r := rand.New(rand.NewSource(opts.Seed))
+		picked := make([][2]int, 0, opts.Trials)
+		for i := 0; i < opts.Trials; i++ {
+			picked = append(picked, pairs[r.Intn(len(pairs))])
+		}
+		pairs = picked
+	}
+	for _, pair := range pairs {
+		if err := checkRecoveryAt(newDriver, scenario, pair[0], pair[1], want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckRecoveryAt runs one trial of CheckRecovery: restore from the checkpoint
+// step at checkpointStep after a crash before the step at crashStep.
+func CheckRecoveryAt(newDriver func() api.Driver, scenario Scenario, checkpointStep int, crashStep int) error {
+	if checkpointStep < 0 || checkpointStep >= len(scenario) || scenario[checkpointStep].Kind != StepCheckpoint {
+		return fmt.Errorf("step %d is not a checkpoint step", checkpointStep)
+	}
+	if crashStep <= checkpointStep || crashStep > len(scenario) {
+		return fmt.Errorf("crash step %d must be after checkpoint step %d and at most %d", crashStep, checkpointStep, len(scenario))
+	}
+	want, err := runReference(newDriver, scenario)
+	if err != nil {
+		return err
+	}
+	return checkRecoveryAt(newDriver, scenario, checkpointStep, crashStep, want)
+}
+
+// RunRecovery calls CheckRecovery and fails the test on error.
+func RunRecovery(t testing.TB, newDriver func() api.Driver, scenario Scenario, opts RecoveryOptions) {
+	t.Helper()
+	if err := CheckRecovery(newDriver, scenario, opts); err != nil {
+		t.Fatalf("fstest recovery: %v", err)
+	}
+}
+
+type recoveryRun struct {
+	emits []Emit
+	state *memory.Snapshot
+}
+
+func runReference(newDriver func() api.Driver, scenario Scenario) (recoveryRun, error) {
+	h := NewHarness(newDriver())
+	transcript, err := h.Run(scenario)
+	if err != nil {
+		return recoveryRun{}, fmt.Errorf("uninterrupted run: %w", err)
+	}
+	return recoveryRun{emits: transcript.Emits(), state: h.Stores().Snapshot()}, nil
+}
+
+// crashPairs lists every (checkpoint step, crash step) pair.
+func crashPairs(scenario Scenario) [][2]int {
+	var pairs [][2]int
+	for c, step := range scenario {
+		if step.Kind != StepCheckpoint || step.ExpectError {
+			continue
+		}
+		for k := c + 1; k <= len(scenario); k++ {
+			pairs = append(pairs, [2]int{c, k})
+		}
+	}
+	return pairs
+}
+
+func checkRecoveryAt(newDriver func() api.Driver, scenario Scenario, checkpointStep int, crashStep int, want recoveryRun) error {
+	fail := func(diff ...string) error {
+		return &RecoveryFailure{CheckpointStep: checkpointStep, CrashStep: crashStep, Diff: diff}
+	}
+
+	// Run until the crash, keeping the checkpoint snapshot and the outputs
+	// committed by it. Outputs after the checkpoint are lost with the crash.
+	crashed := NewHarness(newDriver())
+	var committed []Emit
+	var snapshot *memory.Snapshot
+	var initConfig map[string]string
+	initSeen := false
+	for idx := 0; idx < crashStep; idx++ {
+		step := scenario[idx]
+		result, err := crashed.Step(idx, step)
+		if err != nil {
+			return fail(fmt.Sprintf("run before crash: %v", err))
+		}
+		if idx > checkpointStep {
+			continue
+		}
+		committed = append(committed, result.Emits...)
+		if step.Kind == StepInit {
+			initConfig = step.Config
+			initSeen = true
+		}
+		if idx == checkpointStep {
+			snapshot = crashed.Stores().Snapshot()
+		}
+	}
+	if !initSeen {
+		return fmt.Errorf("no init step before checkpoint step %d", checkpointStep)
+	}
+
+	// Restart: restore the snapshot into a fresh backend, re-init a fresh
+	// driver and replay everything after the checkpoint.
+	restored := NewHarnessWithBackend(newDriver(), memory.NewBackendFromSnapshot(snapshot))
+	if _, err := restored.Step(-1, Init(initConfig)); err != nil {
+		return fail(fmt.Sprintf("init after restore: %v", err))
+	}
+	got := committed
+	for idx := checkpointStep + 1; idx < len(scenario); idx++ {
+		result, err := restored.Step(idx, scenario[idx])
+		if err != nil {
+			return fail(fmt.Sprintf("replay after restore: %v", err))
+		}
+		got = append(got, result.Emits...)
+	}
+
+	var diff []string
+	diff = append(diff, diffEmits(want.emits, got)...)
+	for _, line := range want.state.Diff(restored.Stores().Snapshot()) {
+		diff = append(diff, "state: "+line)
+	}
+	if len(diff) > 0 {
+		return fail(diff...)
+	}
+	return nil
+}
+
+// diffEmits reports the first differing output and any length mismatch.
+func diffEmits(want, got []Emit) []string {
+	for i := 0; i < len(want) && i < len(got); i++ {
+		if !sameEmit(want[i], got[i]) {
+			return []string{fmt.Sprintf("output %d: want %s, got %s", i, describeEmit(want[i]), describeEmit(got[i]))}
+		}
+	}
+	switch {
+	case len(got) < len(want):
+		return []string{fmt.Sprintf("output %d: want %s, got nothing (%d outputs, want %d)", len(got), describeEmit(want[len(got)]), len(got), len(want))}
+	case len(got) > len(want):
+		return []string{fmt.Sprintf("output %d: unexpected %s (%d outputs, want %d)", len(want), describeEmit(got[len(want)]), len(got), len(want))}
+	}
+	return nil
+}
+
+func sameEmit(a, b Emit) bool {
+	return a.Kind == b.Kind && a.TargetID == b.TargetID && a.Watermark == b.Watermark && bytes.Equal(a.Data, b.Data)
+}
+
+func describeEmit(e Emit) string {
+	if e.Kind == EmitWatermark {
+		return fmt.Sprintf("watermark %d to target %d", e.Watermark, e.TargetID)
+	}
+	return fmt.Sprintf("emit %s to target %d", strconv.Quote(string(e.Data)), e.TargetID)
+}
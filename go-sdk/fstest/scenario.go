@@ -0,0 +1,434 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fstest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// StepKind names the processor export a Step invokes.
+type StepKind int
+
+const (
+	StepInit StepKind = iota
+	StepProcess
+	StepWatermark
+	StepCheckpoint
+	StepHeartbeat
+	StepCustom
+	StepExec
+	StepClose
+	StepProcessBatch
+)
+
+var stepNames = map[StepKind]string{
+	StepInit:         "init",
+	StepProcess:      "process",
+	StepWatermark:    "watermark",
+	StepCheckpoint:   "checkpoint",
+	StepHeartbeat:    "heartbeat",
+	StepCustom:       "custom",
+	StepExec:         "exec",
+	StepClose:        "close",
+	StepProcessBatch: "batch",
+}
+
+func (k StepKind) String() string {
+	if name, ok := stepNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Step is one export call in a Scenario.
+type Step struct {
+	Kind         StepKind
+	Config       map[string]string
+	SourceID     uint32
+	Data         []byte
+	Records      [][]byte
+	Watermark    uint64
+	CheckpointID uint64
+	ClassName    string
+	Modules      []api.Module
+	// ExpectError marks a step whose driver callback is expected to return an error.
+	ExpectError bool
+}
+
+// Scenario is an ordered list of export calls replayed against a driver.
+type Scenario []Step
+
+// Init builds an fs-init step.
+func Init(config map[string]string) Step {
+	return Step{Kind: StepInit, Config: cloneStringMap(config)}
+}
+
+// Process builds an fs-process step.
+func Process(sourceID uint32, data []byte) Step {
+	return Step{Kind: StepProcess, SourceID: sourceID, Data: data}
+}
+
+// ProcessBatch builds an fs-process-batch step.
+func ProcessBatch(sourceID uint32, records ...[]byte) Step {
+	return Step{Kind: StepProcessBatch, SourceID: sourceID, Records: records}
+}
+
+// Watermark builds an fs-process-watermark step.
+func Watermark(sourceID uint32, watermark uint64) Step {
+	return Step{Kind: StepWatermark, SourceID: sourceID, Watermark: watermark}
+}
+
+// Checkpoint builds an fs-take-checkpoint step.
+func Checkpoint(checkpointID uint64) Step {
+	return Step{Kind: StepCheckpoint, CheckpointID: checkpointID}
+}
+
+// Heartbeat builds an fs-check-heartbeat step.
+func Heartbeat() Step {
+	return Step{Kind: StepHeartbeat}
+}
+
+// Custom builds an fs-custom step.
+func Custom(payload []byte) Step {
+	return Step{Kind: StepCustom, Data: payload}
+}
+
+// Exec builds an fs-exec step.
+func Exec(className string, modules []api.Module) Step {
+	return Step{Kind: StepExec, ClassName: className, Modules: modules}
+}
+
+// Close builds an fs-close step.
+func Close() Step {
+	return Step{Kind: StepClose}
+}
+
+// WantError returns a copy of the step that expects the driver to fail.
+func (s Step) WantError() Step {
+	s.ExpectError = true
+	return s
+}
+
+// String renders the step in the text script syntax.
+func (s Step) String() string {
+	var b strings.Builder
+	if s.ExpectError {
+		b.WriteByte('!')
+	}
+	b.WriteString(s.Kind.String())
+	switch s.Kind {
+	case StepInit:
+		for _, k := range sortedKeys(s.Config) {
+			fmt.Fprintf(&b, " %s=%s", k, s.Config[k])
+		}
+	case StepProcess:
+		fmt.Fprintf(&b, " %d %s", s.SourceID, strconv.Quote(string(s.Data)))
+	case StepProcessBatch:
+		fmt.Fprintf(&b, " %d", s.SourceID)
+		for _, record := range s.Records {
+			fmt.Fprintf(&b, " %s", strconv.Quote(string(record)))
+		}
+	case StepWatermark:
+		fmt.Fprintf(&b, " %d %d", s.SourceID, s.Watermark)
+	case StepCheckpoint:
+		fmt.Fprintf(&b, " %d", s.CheckpointID)
+	case StepCustom:
+		fmt.Fprintf(&b, " %s", strconv.Quote(string(s.Data)))
+	case StepExec:
+		fmt.Fprintf(&b, " %s", s.ClassName)
+	}
+	return b.String()
+}
+
+// LoadScenario reads a scenario script. Files ending in .json use the JSON
+// syntax (see ParseScenarioJSON); anything else uses the text syntax (see ParseScenario).
+func LoadScenario(path string) (Scenario, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseScenarioJSON(raw)
+	}
+	return ParseScenario(bytes.NewReader(raw))
+}
+
+// ParseScenario parses the text script syntax, one step per line:
+//
+//	# comment
+//	init key_prefix=p: window=10s
+//	process 0 hello world
+//	process 1 "quoted\nbytes"
+//	batch 0 first "second record" third
+//	watermark 0 1700000000000
+//	checkpoint 1
+//	heartbeat
+//	custom ping
+//	exec com.example.Main
+//	close
+//
+// A leading '!' marks a step that is expected to return an error.
+func ParseScenario(r io.Reader) (Scenario, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	out := make(Scenario, 0)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		step, err := parseStepLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("scenario line %d: %w", lineNo, err)
+		}
+		out = append(out, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func parseStepLine(line string) (Step, error) {
+	expectErr := false
+	if strings.HasPrefix(line, "!") {
+		expectErr = true
+		line = strings.TrimSpace(line[1:])
+	}
+	op, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	var step Step
+	switch op {
+	case "init":
+		config := make(map[string]string)
+		for _, field := range strings.Fields(rest) {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				return Step{}, fmt.Errorf("init config entry %q must be key=value", field)
+			}
+			config[k] = v
+		}
+		step = Init(config)
+	case "process":
+		sourceRaw, dataRaw, _ := strings.Cut(rest, " ")
+		sourceID, err := strconv.ParseUint(sourceRaw, 10, 32)
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid source id %q", sourceRaw)
+		}
+		data, err := parsePayload(strings.TrimSpace(dataRaw))
+		if err != nil {
+			return Step{}, err
+		}
+		step = Process(uint32(sourceID), data)
+	case "batch":
+		sourceRaw, recordsRaw, _ := strings.Cut(rest, " ")
+		sourceID, err := strconv.ParseUint(sourceRaw, 10, 32)
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid source id %q", sourceRaw)
+		}
+		records, err := parsePayloads(recordsRaw)
+		if err != nil {
+			return Step{}, err
+		}
+		step = ProcessBatch(uint32(sourceID), records...)
+	case "watermark":
+		fields := strings.Fields(rest)
+		if len(fields) != 2 {
+			return Step{}, fmt.Errorf("watermark needs <source-id> <watermark>")
+		}
+		sourceID, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid source id %q", fields[0])
+		}
+		watermark, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid watermark %q", fields[1])
+		}
+		step = Watermark(uint32(sourceID), watermark)
+	case "checkpoint":
+		checkpointID, err := strconv.ParseUint(rest, 10, 64)
+		if err != nil {
+			return Step{}, fmt.Errorf("invalid checkpoint id %q", rest)
+		}
+		step = Checkpoint(checkpointID)
+	case "heartbeat":
+		step = Heartbeat()
+	case "custom":
+		payload, err := parsePayload(rest)
+		if err != nil {
+			return Step{}, err
+		}
+		step = Custom(payload)
+	case "exec":
+		step = Exec(rest, nil)
+	case "close":
+		step = Close()
+	default:
+		return Step{}, fmt.Errorf("unknown step %q", op)
+	}
+	step.ExpectError = expectErr
+	return step, nil
+}
+
+func parsePayload(raw string) ([]byte, error) {
+	if strings.HasPrefix(raw, "\"") {
+		unquoted, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted payload %s: %w", raw, err)
+		}
+		return []byte(unquoted), nil
+	}
+	return []byte(raw), nil
+}
+
+// parsePayloads splits space-separated payloads; each is a bare word or a
+// Go-quoted string.
+func parsePayloads(raw string) ([][]byte, error) {
+	var out [][]byte
+	for {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return out, nil
+		}
+		token := raw
+		if strings.HasPrefix(raw, "\"") {
+			quoted, err := strconv.QuotedPrefix(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted payload %s: %w", raw, err)
+			}
+			token = quoted
+		} else if idx := strings.IndexByte(raw, ' '); idx >= 0 {
+			token = raw[:idx]
+		}
+		payload, err := parsePayload(token)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, payload)
+		raw = raw[len(token):]
+	}
+}
+
+type jsonStep struct {
+	Op           string            `json:"op"`
+	Config       map[string]string `json:"config,omitempty"`
+	Source       uint32            `json:"source,omitempty"`
+	Data         *string           `json:"data,omitempty"`
+	DataBase64   *string           `json:"data_base64,omitempty"`
+	Records      []string          `json:"records,omitempty"`
+	RecordsB64   []string          `json:"records_base64,omitempty"`
+	Watermark    uint64            `json:"watermark,omitempty"`
+	CheckpointID uint64            `json:"checkpoint_id,omitempty"`
+	ClassName    string            `json:"class_name,omitempty"`
+	ExpectError  bool              `json:"expect_error,omitempty"`
+}
+
+// ParseScenarioJSON parses a JSON array of steps:
+//
+//	[
+//	  {"op": "init", "config": {"key_prefix": "p:"}},
+//	  {"op": "process", "source": 0, "data": "hello"},
+//	  {"op": "process", "source": 1, "data_base64": "AAE="},
+//	  {"op": "batch", "source": 0, "records": ["a", "b"]},
+//	  {"op": "watermark", "source": 0, "watermark": 100},
+//	  {"op": "checkpoint", "checkpoint_id": 1},
+//	  {"op": "heartbeat"},
+//	  {"op": "custom", "data": "ping", "expect_error": true},
+//	  {"op": "close"}
+//	]
+func ParseScenarioJSON(raw []byte) (Scenario, error) {
+	var items []jsonStep
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("parse scenario json: %w", err)
+	}
+	out := make(Scenario, 0, len(items))
+	for idx, item := range items {
+		data, err := item.payload()
+		if err != nil {
+			return nil, fmt.Errorf("scenario step %d: %w", idx, err)
+		}
+		var step Step
+		switch item.Op {
+		case "init":
+			step = Init(item.Config)
+		case "process":
+			step = Process(item.Source, data)
+		case "batch":
+			records, err := item.records()
+			if err != nil {
+				return nil, fmt.Errorf("scenario step %d: %w", idx, err)
+			}
+			step = ProcessBatch(item.Source, records...)
+		case "watermark":
+			step = Watermark(item.Source, item.Watermark)
+		case "checkpoint":
+			step = Checkpoint(item.CheckpointID)
+		case "heartbeat":
+			step = Heartbeat()
+		case "custom":
+			step = Custom(data)
+		case "exec":
+			step = Exec(item.ClassName, nil)
+		case "close":
+			step = Close()
+		default:
+			return nil, fmt.Errorf("scenario step %d: unknown op %q", idx, item.Op)
+		}
+		step.ExpectError = item.ExpectError
+		out = append(out, step)
+	}
+	return out, nil
+}
+
+func (s jsonStep) payload() ([]byte, error) {
+	if s.DataBase64 != nil {
+		decoded, err := base64.StdEncoding.DecodeString(*s.DataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data_base64: %w", err)
+		}
+		return decoded, nil
+	}
+	if s.Data != nil {
+		return []byte(*s.Data), nil
+	}
+	return nil, nil
+}
+
+func (s jsonStep) records() ([][]byte, error) {
+	out := make([][]byte, 0, len(s.Records)+len(s.RecordsB64))
+	for _, record := range s.Records {
+		out = append(out, []byte(record))
+	}
+	for _, encoded := range s.RecordsB64 {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid records_base64: %w", err)
+		}
+		out = append(out, decoded)
+	}
+	return out, nil
+}
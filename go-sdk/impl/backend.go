@@ -0,0 +1,26 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import "github.com/functionstream/function-stream/go-sdk/api"
+
+// HostBackend is the set of host imports the runtime depends on: the collector
+// (emit, emit-watermark) and the kv store (open a store; store and iterator
+// operations go through the returned api.Store). The WIT bindings are the
+// implementation used in wasip2 builds; in-memory, native and recording
+// backends plug in through NewRuntime.
+type HostBackend interface {
+	Emit(targetID uint32, data []byte) error
+	EmitWatermark(targetID uint32, watermark uint64) error
+	OpenStore(name string) (api.Store, error)
+}
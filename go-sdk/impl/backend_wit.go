@@ -0,0 +1,49 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build wasip2
+
+package impl
+
+import (
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/bindings/functionstream/core/collector"
+	"go.bytecodealliance.org/cm"
+)
+
+// witBackend forwards host imports to the generated collector and kv bindings.
+type witBackend struct{}
+
+func (witBackend) Emit(targetID uint32, data []byte) error {
+	collector.Emit(targetID, toList(data))
+	return nil
+}
+
+// EmitBatch lowers records without copying; the emit buffer owns them and
+// does not reuse them.
+func (witBackend) EmitBatch(targetID uint32, records [][]byte) error {
+	lists := make([]cm.List[uint8], len(records))
+	for idx, record := range records {
+		lists[idx] = cm.ToList(record)
+	}
+	collector.EmitBatch(targetID, cm.ToList(lists))
+	return nil
+}
+
+func (witBackend) EmitWatermark(targetID uint32, watermark uint64) error {
+	collector.EmitWatermark(targetID, watermark)
+	return nil
+}
+
+func (witBackend) OpenStore(name string) (api.Store, error) {
+	return newStore(name), nil
+}
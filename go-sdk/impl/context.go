@@ -16,29 +16,43 @@ import (
 	"strings"
 
 	"github.com/functionstream/function-stream/go-sdk/api"
-	"github.com/functionstream/function-stream/go-sdk/bindings/functionstream/core/collector"
 )
 
 type runtimeContext struct {
-	config map[string]string
-	stores map[string]*storeImpl
-	closed bool
+	backend HostBackend
+	config  map[string]string
+	stores  map[string]api.Store
+	buffer  *emitBuffer
+	closed  bool
 }
 
-func newRuntimeContext(config map[string]string) *runtimeContext {
+func newRuntimeContext(backend HostBackend, config map[string]string) *runtimeContext {
 	return &runtimeContext{
-		config: cloneStringMap(config),
-		stores: make(map[string]*storeImpl),
+		backend: backend,
+		config:  cloneStringMap(config),
+		stores:  make(map[string]api.Store),
 	}
 }
 
+// newBufferedContext returns a context that buffers emits until limit bytes
+// are pending or Flush is called; a limit of 0 disables buffering.
+func newBufferedContext(backend HostBackend, config map[string]string, limit int) *runtimeContext {
+	ctx := newRuntimeContext(backend, config)
+	if limit > 0 {
+		ctx.buffer = newEmitBuffer(backend, limit)
+	}
+	return ctx
+}
+
 func (c *runtimeContext) Emit(targetID uint32, data []byte) error {
 	closed := c.closed
 	if closed {
 		return api.NewError(api.ErrRuntimeClosed, "emit on closed context")
 	}
-	collector.Emit(targetID, toList(data))
-	return nil
+	if c.buffer != nil {
+		return c.buffer.add(targetID, data)
+	}
+	return c.backend.Emit(targetID, data)
 }
 
 func (c *runtimeContext) EmitWatermark(targetID uint32, watermark uint64) error {
@@ -46,8 +60,25 @@ func (c *runtimeContext) EmitWatermark(targetID uint32, watermark uint64) error
 	if closed {
 		return api.NewError(api.ErrRuntimeClosed, "emit watermark on closed context")
 	}
-	collector.EmitWatermark(targetID, watermark)
-	return nil
+	if err := c.Flush(); err != nil {
+		return err
+	}
+	return c.backend.EmitWatermark(targetID, watermark)
+}
+
+// Flush sends any buffered emits to the host. It implements api.Flusher.
+func (c *runtimeContext) Flush() error {
+	if c.buffer == nil {
+		return nil
+	}
+	return c.buffer.flush()
+}
+
+// discard drops buffered emits without sending them.
+func (c *runtimeContext) discard() {
+	if c.buffer != nil {
+		c.buffer.reset()
+	}
 }
 
 func (c *runtimeContext) GetOrCreateStore(name string) (api.Store, error) {
@@ -63,7 +94,10 @@ func (c *runtimeContext) GetOrCreateStore(name string) (api.Store, error) {
 		return existing, nil
 	}
 
-	store := newStore(storeName)
+	store, err := c.backend.OpenStore(storeName)
+	if err != nil {
+		return nil, err
+	}
 	c.stores[storeName] = store
 	return store, nil
 }
@@ -76,11 +110,11 @@ func (c *runtimeContext) Close() error {
 	if c.closed {
 		return nil
 	}
+	firstErr := c.Flush()
 	c.closed = true
 	stores := c.stores
-	c.stores = make(map[string]*storeImpl)
+	c.stores = make(map[string]api.Store)
 
-	var firstErr error
 	for _, store := range stores {
 		if err := store.Close(); err != nil && firstErr == nil {
 			firstErr = err
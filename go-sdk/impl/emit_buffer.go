@@ -0,0 +1,121 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// ConfigEmitBufferBytes is the init config key that turns on buffered emits.
+// Its value is the number of buffered payload bytes that triggers a flush;
+// unset or 0 emits every record immediately, unless the failure policy
+// buffers each attempt.
+const ConfigEmitBufferBytes = "emit_buffer_bytes"
+
+// BatchEmitter is an optional HostBackend extension that delivers several
+// records for one target in a single host call. Backends without it get
+// buffered records through Emit, one at a time.
+type BatchEmitter interface {
+	EmitBatch(targetID uint32, records [][]byte) error
+}
+
+func parseEmitBufferBytes(config map[string]string) (int, error) {
+	raw := strings.TrimSpace(config[ConfigEmitBufferBytes])
+	if raw == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 0 {
+		return 0, api.NewError(api.ErrRuntimeInvalidConfig,
+			"%s must be a non-negative integer, got %q", ConfigEmitBufferBytes, raw)
+	}
+	return limit, nil
+}
+
+// emitBuffer gathers emits in one buffer, in emit order. A flush sends each
+// run of consecutive records for the same target as one batch, so records
+// reach the host in the order they were emitted, across targets too.
+// Watermarks are never buffered: the context flushes everything before
+// forwarding one, so no record overtakes a watermark emitted after it.
+type emitBuffer struct {
+	backend HostBackend
+	limit   int
+	data    []byte
+	records []pendingEmit
+}
+
+// pendingEmit is a buffered record: its target and where its payload ends in
+// emitBuffer.data.
+type pendingEmit struct {
+	targetID uint32
+	end      int
+}
+
+func newEmitBuffer(backend HostBackend, limit int) *emitBuffer {
+	return &emitBuffer{backend: backend, limit: limit}
+}
+
+func (b *emitBuffer) add(targetID uint32, data []byte) error {
+	b.data = append(b.data, data...)
+	b.records = append(b.records, pendingEmit{targetID: targetID, end: len(b.data)})
+	if len(b.data) >= b.limit {
+		return b.flush()
+	}
+	return nil
+}
+
+// reset drops everything buffered.
+func (b *emitBuffer) reset() {
+	b.data, b.records = nil, nil
+}
+
+// flush sends everything buffered. Records are dropped from the buffer even
+// when sending fails, so a failed flush is not repeated.
+func (b *emitBuffer) flush() error {
+	data, pending := b.data, b.records
+	b.reset()
+	var firstErr error
+	start := 0
+	for len(pending) > 0 {
+		targetID := pending[0].targetID
+		run := 1
+		for run < len(pending) && pending[run].targetID == targetID {
+			run++
+		}
+		records := make([][]byte, run)
+		for idx, record := range pending[:run] {
+			records[idx] = data[start:record.end:record.end]
+			start = record.end
+		}
+		if err := b.send(targetID, records); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		pending = pending[run:]
+	}
+	return firstErr
+}
+
+func (b *emitBuffer) send(targetID uint32, records [][]byte) error {
+	if batcher, ok := b.backend.(BatchEmitter); ok {
+		return batcher.EmitBatch(targetID, records)
+	}
+	for _, record := range records {
+		if err := b.backend.Emit(targetID, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
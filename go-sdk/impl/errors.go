@@ -0,0 +1,41 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import (
+	"errors"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// errorDetails is what an export reports to the host when the driver fails.
+type errorDetails struct {
+	code      api.ErrorCode
+	message   string
+	retryable bool
+}
+
+// describeError reports the code of an SDKError anywhere in the chain, or
+// ErrDriver for other errors. The message is the full error text, minus the
+// code prefix when err is the SDKError itself.
+func describeError(err error) errorDetails {
+	details := errorDetails{code: api.ErrDriver, message: err.Error(), retryable: api.IsRetryable(err)}
+	var sdkErr *api.SDKError
+	if errors.As(err, &sdkErr) {
+		details.code = sdkErr.Code
+		if err == error(sdkErr) {
+			details.message = sdkErr.Message
+		}
+	}
+	return details
+}
@@ -0,0 +1,229 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Init config keys that select the per-record failure policy.
+const (
+	ConfigFailurePolicy    = "failure_policy"
+	ConfigFailureRetries   = "failure_retries"
+	ConfigDeadLetterTarget = "dead_letter_target"
+)
+
+// FailureMode is what the guest runtime does when a record callback
+// (Process, ProcessBatch or ProcessWatermark) returns an error or panics.
+type FailureMode string
+
+const (
+	// FailureFail returns the failure to the host (the default).
+	FailureFail FailureMode = "fail"
+	// FailureSkip drops the record, counts it and reports success.
+	FailureSkip FailureMode = "skip"
+	// FailureRetry calls the driver again with the same record up to Retries
+	// more times, then returns the last failure to the host.
+	FailureRetry FailureMode = "retry"
+	// FailureDeadLetter retries like FailureRetry (no retries by default),
+	// then emits an api.DeadLetter envelope for the record on DeadLetterTarget
+	// and reports success. Watermark failures are returned to the host.
+	FailureDeadLetter FailureMode = "dead_letter"
+)
+
+// defaultFailureRetries is used by FailureRetry when failure_retries is unset.
+const defaultFailureRetries = 3
+
+// FailurePolicy is the per-record failure handling chosen at init.
+type FailurePolicy struct {
+	Mode             FailureMode
+	Retries          int
+	DeadLetterTarget uint32
+}
+
+// ParseFailurePolicy reads failure_policy, failure_retries and
+// dead_letter_target from the init config. Missing keys select FailureFail.
+func ParseFailurePolicy(config map[string]string) (FailurePolicy, error) {
+	policy := FailurePolicy{Mode: FailureFail}
+	if raw := strings.TrimSpace(config[ConfigFailurePolicy]); raw != "" {
+		policy.Mode = FailureMode(strings.ToLower(raw))
+	}
+	switch policy.Mode {
+	case FailureFail, FailureSkip, FailureDeadLetter:
+	case FailureRetry:
+		policy.Retries = defaultFailureRetries
+	default:
+		return FailurePolicy{}, api.NewError(api.ErrRuntimeInvalidConfig,
+			"%s must be %q, %q, %q or %q, got %q", ConfigFailurePolicy,
+			FailureFail, FailureSkip, FailureRetry, FailureDeadLetter, policy.Mode)
+	}
+	raw := strings.TrimSpace(config[ConfigDeadLetterTarget])
+	switch {
+	case policy.Mode == FailureDeadLetter && raw == "":
+		return FailurePolicy{}, api.NewError(api.ErrRuntimeInvalidConfig,
+			"%s=%q requires %s", ConfigFailurePolicy, FailureDeadLetter, ConfigDeadLetterTarget)
+	case policy.Mode != FailureDeadLetter && raw != "":
+		return FailurePolicy{}, api.NewError(api.ErrRuntimeInvalidConfig,
+			"%s requires %s=%q", ConfigDeadLetterTarget, ConfigFailurePolicy, FailureDeadLetter)
+	case raw != "":
+		target, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return FailurePolicy{}, api.NewError(api.ErrRuntimeInvalidConfig,
+				"%s must be a target ID, got %q", ConfigDeadLetterTarget, raw)
+		}
+		policy.DeadLetterTarget = uint32(target)
+	}
+	if raw := strings.TrimSpace(config[ConfigFailureRetries]); raw != "" {
+		if policy.Mode != FailureRetry && policy.Mode != FailureDeadLetter {
+			return FailurePolicy{}, api.NewError(api.ErrRuntimeInvalidConfig,
+				"%s requires %s=%q or %q", ConfigFailureRetries, ConfigFailurePolicy, FailureRetry, FailureDeadLetter)
+		}
+		retries, err := strconv.Atoi(raw)
+		if err != nil || retries < 0 {
+			return FailurePolicy{}, api.NewError(api.ErrRuntimeInvalidConfig,
+				"%s must be a non-negative integer, got %q", ConfigFailureRetries, raw)
+		}
+		policy.Retries = retries
+	}
+	return policy, nil
+}
+
+// callGuarded runs a driver callback and turns a panic into an ErrDriverPanic
+// SDKError carrying the panic value and stack.
+func callGuarded(callback string, fn func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = api.NewError(api.ErrDriverPanic, "driver panicked in %s: %v\n%s", callback, recovered, debug.Stack())
+		}
+	}()
+	return fn()
+}
+
+// failureGuard applies a FailurePolicy to record callbacks.
+type failureGuard struct {
+	policy  FailurePolicy
+	skipped uint64
+	log     io.Writer
+	// emit sends dead-letter envelopes, through the emit buffer when one is
+	// configured; watermarks holds the latest watermark received per source
+	// for them.
+	emit       func(targetID uint32, data []byte) error
+	watermarks map[uint32]uint64
+	// discard drops the emits buffered by a failed attempt, so a retried or
+	// dead-lettered record does not also deliver partial output.
+	discard func()
+}
+
+// process runs a Process callback for the record under the policy. fn must be
+// safe to call again with the same record.
+func (g *failureGuard) process(sourceID uint32, data []byte, fn func() error) error {
+	attempts, err := g.attempt("Process", fn)
+	if err == nil {
+		return nil
+	}
+	switch g.policy.Mode {
+	case FailureSkip:
+		return g.skip("Process", 1, err)
+	case FailureDeadLetter:
+		return g.deadLetter(sourceID, data, attempts, err)
+	}
+	return err
+}
+
+// processBatch runs a ProcessBatch callback under the policy. The batch is
+// retried, skipped or dead-lettered as a unit: every record in it gets an
+// envelope carrying the batch error.
+func (g *failureGuard) processBatch(sourceID uint32, records [][]byte, fn func() error) error {
+	attempts, batchErr := g.attempt("ProcessBatch", fn)
+	if batchErr == nil {
+		return nil
+	}
+	switch g.policy.Mode {
+	case FailureSkip:
+		return g.skip("ProcessBatch", uint64(len(records)), batchErr)
+	case FailureDeadLetter:
+		for _, record := range records {
+			if err := g.deadLetter(sourceID, record, attempts, batchErr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return batchErr
+}
+
+// watermark runs a ProcessWatermark callback under the policy.
+func (g *failureGuard) watermark(sourceID uint32, watermark uint64, fn func() error) error {
+	if g.watermarks == nil {
+		g.watermarks = make(map[uint32]uint64)
+	}
+	g.watermarks[sourceID] = watermark
+	_, err := g.attempt("ProcessWatermark", fn)
+	if err != nil && g.policy.Mode == FailureSkip {
+		return g.skip("ProcessWatermark", 1, err)
+	}
+	return err
+}
+
+// attempt calls fn once plus the policy's retries, stopping at the first
+// success, and returns the number of calls made and the last error.
+func (g *failureGuard) attempt(callback string, fn func() error) (int, error) {
+	attempts := 1
+	if g.policy.Mode == FailureRetry || g.policy.Mode == FailureDeadLetter {
+		attempts += g.policy.Retries
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = callGuarded(callback, fn); err == nil {
+			return attempt, nil
+		}
+		if g.discard != nil {
+			g.discard()
+		}
+	}
+	return attempts, err
+}
+
+func (g *failureGuard) skip(callback string, records uint64, err error) error {
+	g.skipped += records
+	if g.log != nil {
+		fmt.Fprintf(g.log, "fssdk: skipped %d record(s) after %s failed (%d skipped so far): %v\n", records, callback, g.skipped, err)
+	}
+	return nil
+}
+
+func (g *failureGuard) deadLetter(sourceID uint32, data []byte, attempts int, cause error) error {
+	details := describeError(cause)
+	envelope := api.DeadLetter{
+		SourceID:     sourceID,
+		Data:         data,
+		ErrorCode:    details.code,
+		ErrorMessage: details.message,
+		Attempts:     attempts,
+	}
+	if watermark, ok := g.watermarks[sourceID]; ok {
+		envelope.Watermark = &watermark
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return api.NewError(api.ErrResultUnexpected, "encode dead letter: %v", err)
+	}
+	return g.emit(g.policy.DeadLetterTarget, payload)
+}
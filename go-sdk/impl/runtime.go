@@ -13,116 +13,198 @@
 package impl
 
 import (
+	"math"
+	"os"
+
 	"github.com/functionstream/function-stream/go-sdk/api"
-	"github.com/functionstream/function-stream/go-sdk/bindings/functionstream/core/processor"
-	"go.bytecodealliance.org/cm"
 )
 
-type guestRuntime struct {
-	driver api.Driver
-	ctx    *runtimeContext
+// Runtime drives a Driver through the processor lifecycle against a
+// HostBackend. Init swaps in a fresh context and closes the previous one; the
+// other calls reuse the current context; Close closes it. Driver panics are
+// recovered into ErrDriverPanic errors, and record callbacks follow the
+// failure policy selected by the init config, which may route failed records
+// to a dead-letter target. When the init config sets ConfigEmitBufferBytes,
+// emits are buffered and flushed at the end of every successful call, before
+// TakeCheckpoint reaches the driver, and when the buffer fills up; the emits a
+// failed call or attempt buffered are dropped. Under the skip, retry and
+// dead_letter policies every attempt is buffered in full, with or without
+// ConfigEmitBufferBytes.
+type Runtime struct {
+	driver    api.Driver
+	backend   HostBackend
+	ctx       *runtimeContext
+	emitLimit int
+	guard     failureGuard
 }
 
-// Run wires the driver to the WASM processor exports. Call from the main package (e.g. fssdk.Run(driver)).
-func Run(driver api.Driver) {
+// NewRuntime creates a runtime for driver on top of backend. Records skipped
+// by the failure policy are logged to stderr.
+func NewRuntime(driver api.Driver, backend HostBackend) (*Runtime, error) {
 	if driver == nil {
-		panic(api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil"))
+		return nil, api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil")
 	}
-
-	rt := &guestRuntime{
-		driver: driver,
-		ctx:    newRuntimeContext(map[string]string{}),
+	if backend == nil {
+		return nil, api.NewError(api.ErrRuntimeNotInitialized, "host backend must not be nil")
 	}
-
-	processor.Exports.FsInit = rt.fsInit
-	processor.Exports.FsProcess = rt.fsProcess
-	processor.Exports.FsProcessWatermark = rt.fsProcessWatermark
-	processor.Exports.FsTakeCheckpoint = rt.fsTakeCheckpoint
-	processor.Exports.FsCheckHeartbeat = rt.fsCheckHeartbeat
-	processor.Exports.FsClose = rt.fsClose
-	processor.Exports.FsExec = rt.fsExec
-	processor.Exports.FsCustom = rt.fsCustom
+	r := &Runtime{driver: driver, backend: backend}
+	r.guard = failureGuard{
+		policy:  FailurePolicy{Mode: FailureFail},
+		log:     os.Stderr,
+		emit:    func(targetID uint32, data []byte) error { return r.context().Emit(targetID, data) },
+		discard: func() { r.context().discard() },
+	}
+	return r, nil
 }
 
-func (r *guestRuntime) fsInit(config cm.List[[2]string]) {
-	cfg := liftConfig(config)
-	newCtx := newRuntimeContext(cfg)
+func (r *Runtime) Init(config map[string]string) error {
+	limit, err := parseEmitBufferBytes(config)
+	if err != nil {
+		return err
+	}
+	policy, err := ParseFailurePolicy(config)
+	if err != nil {
+		return err
+	}
+	r.emitLimit = attemptEmitLimit(limit, policy)
+	r.guard.policy = policy
+	newCtx := newBufferedContext(r.backend, config, r.emitLimit)
 	oldCtx := r.swapContext(newCtx)
 	if oldCtx != nil {
 		_ = oldCtx.Close()
 	}
-	if err := r.driver.Init(newCtx, cfg); err != nil {
-		panic(err)
-	}
+	return r.flushAfter(callGuarded("Init", func() error {
+		return r.driver.Init(newCtx, cloneStringMap(config))
+	}))
 }
 
-func (r *guestRuntime) fsProcess(sourceID uint32, data cm.List[uint8]) {
-	ctx := r.context()
-	if err := r.driver.Process(ctx, sourceID, cloneBytes(data.Slice())); err != nil {
-		panic(err)
+// attemptEmitLimit is the emit buffer limit for policy. Policies that recover
+// from a failed record hold every emit of an attempt until it succeeds, so
+// the output of a failed attempt never reaches the host; the
+// ConfigEmitBufferBytes limit then no longer flushes in the middle of one.
+func attemptEmitLimit(limit int, policy FailurePolicy) int {
+	if policy.Mode == FailureFail {
+		return limit
 	}
+	return math.MaxInt
 }
 
-func (r *guestRuntime) fsProcessWatermark(sourceID uint32, watermark uint64) {
-	ctx := r.context()
-	if err := r.driver.ProcessWatermark(ctx, sourceID, watermark); err != nil {
-		panic(err)
+// Skipped returns how many records the skip failure policy has dropped
+// since the runtime was created.
+func (r *Runtime) Skipped() uint64 {
+	return r.guard.skipped
+}
+
+func (r *Runtime) Process(sourceID uint32, data []byte) error {
+	return r.flushAfter(r.guard.process(sourceID, data, func() error {
+		return r.driver.Process(r.context(), sourceID, data)
+	}))
+}
+
+// ProcessBatch hands records to the driver's ProcessBatch, where the failure
+// policy applies to the batch as a unit, or, when the driver is not an
+// api.BatchProcessor, to Process one record at a time with the policy
+// applied per record.
+func (r *Runtime) ProcessBatch(sourceID uint32, records [][]byte) error {
+	if batcher, ok := r.driver.(api.BatchProcessor); ok {
+		return r.flushAfter(r.guard.processBatch(sourceID, records, func() error {
+			return batcher.ProcessBatch(r.context(), sourceID, records)
+		}))
 	}
+	for _, record := range records {
+		if err := r.Process(sourceID, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runtime) ProcessWatermark(sourceID uint32, watermark uint64) error {
+	return r.flushAfter(r.guard.watermark(sourceID, watermark, func() error {
+		return r.driver.ProcessWatermark(r.context(), sourceID, watermark)
+	}))
 }
 
-func (r *guestRuntime) fsTakeCheckpoint(checkpointID uint64) {
+// TakeCheckpoint flushes buffered emits before the driver snapshots its
+// state, so everything emitted before the checkpoint reaches the host first.
+func (r *Runtime) TakeCheckpoint(checkpointID uint64) error {
 	ctx := r.context()
-	if err := r.driver.TakeCheckpoint(ctx, checkpointID); err != nil {
-		panic(err)
+	if err := ctx.Flush(); err != nil {
+		return err
 	}
+	return r.flushAfter(callGuarded("TakeCheckpoint", func() error {
+		return r.driver.TakeCheckpoint(ctx, checkpointID)
+	}))
 }
 
-func (r *guestRuntime) fsCheckHeartbeat() bool {
-	return r.driver.CheckHeartbeat(r.context())
+func (r *Runtime) CheckHeartbeat() bool {
+	var healthy bool
+	err := callGuarded("CheckHeartbeat", func() error {
+		healthy = r.driver.CheckHeartbeat(r.context())
+		return nil
+	})
+	return r.flushAfter(err) == nil && healthy
 }
 
-func (r *guestRuntime) fsClose() {
+// Close closes the driver and then the context. As in flushAfter, buffered
+// emits are sent only when the driver's Close succeeded.
+func (r *Runtime) Close() error {
 	ctx := r.context()
-	driverErr := r.driver.Close(ctx)
-	ctxErr := r.closeContext()
+	driverErr := callGuarded("Close", func() error {
+		return r.driver.Close(ctx)
+	})
 	if driverErr != nil {
-		panic(driverErr)
+		ctx.discard()
 	}
-	if ctxErr != nil {
-		panic(ctxErr)
+	ctxErr := r.closeContext()
+	if driverErr != nil {
+		return driverErr
 	}
+	return ctxErr
 }
 
-func (r *guestRuntime) fsExec(className string, modules cm.List[cm.Tuple[string, cm.List[uint8]]]) {
-	ctx := r.context()
-	if err := r.driver.Exec(ctx, className, liftModules(modules)); err != nil {
-		panic(err)
-	}
+func (r *Runtime) Exec(className string, modules []api.Module) error {
+	return r.flushAfter(callGuarded("Exec", func() error {
+		return r.driver.Exec(r.context(), className, modules)
+	}))
 }
 
-func (r *guestRuntime) fsCustom(payload cm.List[uint8]) cm.List[uint8] {
-	ctx := r.context()
-	result, err := r.driver.Custom(ctx, cloneBytes(payload.Slice()))
+func (r *Runtime) Custom(payload []byte) ([]byte, error) {
+	var response []byte
+	err := callGuarded("Custom", func() (err error) {
+		response, err = r.driver.Custom(r.context(), payload)
+		return err
+	})
+	return response, r.flushAfter(err)
+}
+
+// flushAfter ends a call: it flushes buffered emits when the call succeeded
+// and drops them when it failed, returning the call's error first.
+func (r *Runtime) flushAfter(err error) error {
+	if r.ctx == nil {
+		return err
+	}
 	if err != nil {
-		panic(err)
+		r.ctx.discard()
+		return err
 	}
-	return toList(result)
+	return r.ctx.Flush()
 }
 
-func (r *guestRuntime) context() *runtimeContext {
+func (r *Runtime) context() *runtimeContext {
 	if r.ctx == nil {
-		r.ctx = newRuntimeContext(map[string]string{})
+		r.ctx = newBufferedContext(r.backend, map[string]string{}, r.emitLimit)
 	}
 	return r.ctx
 }
 
-func (r *guestRuntime) swapContext(next *runtimeContext) *runtimeContext {
+func (r *Runtime) swapContext(next *runtimeContext) *runtimeContext {
 	previous := r.ctx
 	r.ctx = next
 	return previous
 }
 
-func (r *guestRuntime) closeContext() error {
+func (r *Runtime) closeContext() error {
 	ctx := r.ctx
 	r.ctx = nil
 	if ctx == nil {
@@ -130,24 +212,3 @@ func (r *guestRuntime) closeContext() error {
 	}
 	return ctx.Close()
 }
-
-func liftConfig(config cm.List[[2]string]) map[string]string {
-	items := config.Slice()
-	out := make(map[string]string, len(items))
-	for idx := range items {
-		out[items[idx][0]] = items[idx][1]
-	}
-	return out
-}
-
-func liftModules(modules cm.List[cm.Tuple[string, cm.List[uint8]]]) []api.Module {
-	items := modules.Slice()
-	out := make([]api.Module, len(items))
-	for idx := range items {
-		out[idx] = api.Module{
-			Name:  items[idx].F0,
-			Bytes: cloneBytes(items[idx].F1.Slice()),
-		}
-	}
-	return out
-}
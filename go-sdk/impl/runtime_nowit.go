@@ -0,0 +1,26 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !wasip2
+
+package impl
+
+import "github.com/functionstream/function-stream/go-sdk/api"
+
+// Run validates the driver. Outside a wasip2 build there are no processor
+// exports to wire, so package main can still be built and tested with plain
+// go test; use NewRuntime with a HostBackend to drive the lifecycle.
+func Run(driver api.Driver) {
+	if driver == nil {
+		panic(api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil"))
+	}
+}
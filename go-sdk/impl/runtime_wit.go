@@ -0,0 +1,144 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build wasip2
+
+package impl
+
+import (
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/bindings/functionstream/core/processor"
+	"go.bytecodealliance.org/cm"
+)
+
+// guestRuntime adapts Runtime to the processor exports.
+type guestRuntime struct {
+	rt *Runtime
+}
+
+// Run wires the driver to the WASM processor exports. Call from the main package (e.g. fssdk.Run(driver)).
+func Run(driver api.Driver) {
+	rt, err := NewRuntime(driver, witBackend{})
+	if err != nil {
+		panic(err)
+	}
+	g := &guestRuntime{rt: rt}
+
+	processor.Exports.FsInit = g.fsInit
+	processor.Exports.FsProcess = g.fsProcess
+	processor.Exports.FsProcessBatch = g.fsProcessBatch
+	processor.Exports.FsProcessWatermark = g.fsProcessWatermark
+	processor.Exports.FsTakeCheckpoint = g.fsTakeCheckpoint
+	processor.Exports.FsCheckHeartbeat = g.fsCheckHeartbeat
+	processor.Exports.FsClose = g.fsClose
+	processor.Exports.FsExec = g.fsExec
+	processor.Exports.FsCustom = g.fsCustom
+}
+
+// exportResult is the WIT result<_, error-info> returned by the exports.
+type exportResult = cm.Result[processor.ErrorInfo, struct{}, processor.ErrorInfo]
+
+func (g *guestRuntime) fsInit(config cm.List[[2]string]) exportResult {
+	return lowerResult(g.rt.Init(liftConfig(config)))
+}
+
+func (g *guestRuntime) fsProcess(sourceID uint32, data cm.List[uint8]) exportResult {
+	return lowerResult(g.rt.Process(sourceID, cloneBytes(data.Slice())))
+}
+
+// fsProcessBatch copies the records once into a shared buffer.
+func (g *guestRuntime) fsProcessBatch(sourceID uint32, records cm.List[cm.List[uint8]]) exportResult {
+	return lowerResult(g.rt.ProcessBatch(sourceID, liftRecords(records)))
+}
+
+func (g *guestRuntime) fsProcessWatermark(sourceID uint32, watermark uint64) exportResult {
+	return lowerResult(g.rt.ProcessWatermark(sourceID, watermark))
+}
+
+func (g *guestRuntime) fsTakeCheckpoint(checkpointID uint64) exportResult {
+	return lowerResult(g.rt.TakeCheckpoint(checkpointID))
+}
+
+func (g *guestRuntime) fsCheckHeartbeat() bool {
+	return g.rt.CheckHeartbeat()
+}
+
+func (g *guestRuntime) fsClose() exportResult {
+	return lowerResult(g.rt.Close())
+}
+
+func (g *guestRuntime) fsExec(className string, modules cm.List[cm.Tuple[string, cm.List[uint8]]]) exportResult {
+	return lowerResult(g.rt.Exec(className, liftModules(modules)))
+}
+
+func (g *guestRuntime) fsCustom(payload cm.List[uint8]) (result cm.Result[processor.ErrorInfoShape, cm.List[uint8], processor.ErrorInfo]) {
+	response, err := g.rt.Custom(cloneBytes(payload.Slice()))
+	if err != nil {
+		result.SetErr(lowerError(err))
+		return result
+	}
+	result.SetOK(toList(response))
+	return result
+}
+
+func lowerResult(err error) (result exportResult) {
+	if err != nil {
+		result.SetErr(lowerError(err))
+	}
+	return result
+}
+
+func lowerError(err error) processor.ErrorInfo {
+	details := describeError(err)
+	return processor.ErrorInfo{
+		Code:      string(details.code),
+		Message:   details.message,
+		Retryable: details.retryable,
+	}
+}
+
+func liftConfig(config cm.List[[2]string]) map[string]string {
+	items := config.Slice()
+	out := make(map[string]string, len(items))
+	for idx := range items {
+		out[items[idx][0]] = items[idx][1]
+	}
+	return out
+}
+
+func liftRecords(records cm.List[cm.List[uint8]]) [][]byte {
+	items := records.Slice()
+	total := 0
+	for idx := range items {
+		total += int(items[idx].Len())
+	}
+	buf := make([]byte, 0, total)
+	out := make([][]byte, len(items))
+	for idx := range items {
+		start := len(buf)
+		buf = append(buf, items[idx].Slice()...)
+		out[idx] = buf[start:len(buf):len(buf)]
+	}
+	return out
+}
+
+func liftModules(modules cm.List[cm.Tuple[string, cm.List[uint8]]]) []api.Module {
+	items := modules.Slice()
+	out := make([]api.Module, len(items))
+	for idx := range items {
+		out[idx] = api.Module{
+			Name:  items[idx].F0,
+			Bytes: cloneBytes(items[idx].F1.Slice()),
+		}
+	}
+	return out
+}
@@ -10,6 +10,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+//go:build wasip2
+
 package impl
 
 import (
@@ -25,12 +27,14 @@ type storeImpl struct {
 	name      string
 	raw       kv.Store
 	closeOnce sync.Once
+	closed    bool
 }
 
 type iteratorImpl struct {
 	name      string
 	raw       kv.Iterator
 	closeOnce sync.Once
+	closed    bool
 }
 
 func newStore(name string) *storeImpl {
@@ -41,6 +45,9 @@ func newStore(name string) *storeImpl {
 }
 
 func (s *storeImpl) PutState(key []byte, value []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	result := s.raw.PutState(toList(key), toList(value))
 	if kvErr := result.Err(); kvErr != nil {
 		return mapKVError(s.name, *kvErr)
@@ -49,6 +56,9 @@ func (s *storeImpl) PutState(key []byte, value []byte) error {
 }
 
 func (s *storeImpl) GetState(key []byte) ([]byte, bool, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, false, err
+	}
 	result := s.raw.GetState(toList(key))
 	if kvErr := result.Err(); kvErr != nil {
 		return nil, false, mapKVError(s.name, *kvErr)
@@ -65,6 +75,9 @@ func (s *storeImpl) GetState(key []byte) ([]byte, bool, error) {
 }
 
 func (s *storeImpl) DeleteState(key []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	result := s.raw.DeleteState(toList(key))
 	if kvErr := result.Err(); kvErr != nil {
 		return mapKVError(s.name, *kvErr)
@@ -73,6 +86,9 @@ func (s *storeImpl) DeleteState(key []byte) error {
 }
 
 func (s *storeImpl) ListStates(startInclusive []byte, endExclusive []byte) ([][]byte, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
 	result := s.raw.ListStates(toList(startInclusive), toList(endExclusive))
 	if kvErr := result.Err(); kvErr != nil {
 		return nil, mapKVError(s.name, *kvErr)
@@ -85,6 +101,9 @@ func (s *storeImpl) ListStates(startInclusive []byte, endExclusive []byte) ([][]
 }
 
 func (s *storeImpl) Put(key api.ComplexKey, value []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	result := s.raw.Put(toKVComplexKey(key), toList(value))
 	if kvErr := result.Err(); kvErr != nil {
 		return mapKVError(s.name, *kvErr)
@@ -93,6 +112,9 @@ func (s *storeImpl) Put(key api.ComplexKey, value []byte) error {
 }
 
 func (s *storeImpl) Get(key api.ComplexKey) ([]byte, bool, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, false, err
+	}
 	result := s.raw.Get(toKVComplexKey(key))
 	if kvErr := result.Err(); kvErr != nil {
 		return nil, false, mapKVError(s.name, *kvErr)
@@ -109,6 +131,9 @@ func (s *storeImpl) Get(key api.ComplexKey) ([]byte, bool, error) {
 }
 
 func (s *storeImpl) Delete(key api.ComplexKey) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	result := s.raw.Delete(toKVComplexKey(key))
 	if kvErr := result.Err(); kvErr != nil {
 		return mapKVError(s.name, *kvErr)
@@ -117,6 +142,9 @@ func (s *storeImpl) Delete(key api.ComplexKey) error {
 }
 
 func (s *storeImpl) Merge(key api.ComplexKey, value []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	result := s.raw.Merge(toKVComplexKey(key), toList(value))
 	if kvErr := result.Err(); kvErr != nil {
 		return mapKVError(s.name, *kvErr)
@@ -125,6 +153,9 @@ func (s *storeImpl) Merge(key api.ComplexKey, value []byte) error {
 }
 
 func (s *storeImpl) DeletePrefix(key api.ComplexKey) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
 	result := s.raw.DeletePrefix(toKVComplexKey(key))
 	if kvErr := result.Err(); kvErr != nil {
 		return mapKVError(s.name, *kvErr)
@@ -139,6 +170,9 @@ func (s *storeImpl) ListComplex(
 	startInclusive []byte,
 	endExclusive []byte,
 ) ([][]byte, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
 	result := s.raw.ListComplex(
 		toList(keyGroup),
 		toList(key),
@@ -157,6 +191,9 @@ func (s *storeImpl) ListComplex(
 }
 
 func (s *storeImpl) ScanComplex(keyGroup []byte, key []byte, namespace []byte) (api.Iterator, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
 	result := s.raw.ScanComplex(toList(keyGroup), toList(key), toList(namespace))
 	if kvErr := result.Err(); kvErr != nil {
 		return nil, mapKVError(s.name, *kvErr)
@@ -173,12 +210,23 @@ func (s *storeImpl) ScanComplex(keyGroup []byte, key []byte, namespace []byte) (
 
 func (s *storeImpl) Close() error {
 	s.closeOnce.Do(func() {
+		s.closed = true
 		s.raw.ResourceDrop()
 	})
 	return nil
 }
 
+func (s *storeImpl) checkOpen() error {
+	if s.closed {
+		return api.NewError(api.ErrRuntimeClosed, "store %q is closed", s.name)
+	}
+	return nil
+}
+
 func (i *iteratorImpl) HasNext() (bool, error) {
+	if err := i.checkOpen(); err != nil {
+		return false, err
+	}
 	result := i.raw.HasNext()
 	if kvErr := result.Err(); kvErr != nil {
 		return false, mapKVError(i.name, *kvErr)
@@ -191,6 +239,9 @@ func (i *iteratorImpl) HasNext() (bool, error) {
 }
 
 func (i *iteratorImpl) Next() ([]byte, []byte, bool, error) {
+	if err := i.checkOpen(); err != nil {
+		return nil, nil, false, err
+	}
 	result := i.raw.Next()
 	if kvErr := result.Err(); kvErr != nil {
 		return nil, nil, false, mapKVError(i.name, *kvErr)
@@ -209,11 +260,19 @@ func (i *iteratorImpl) Next() ([]byte, []byte, bool, error) {
 
 func (i *iteratorImpl) Close() error {
 	i.closeOnce.Do(func() {
+		i.closed = true
 		i.raw.ResourceDrop()
 	})
 	return nil
 }
 
+func (i *iteratorImpl) checkOpen() error {
+	if i.closed {
+		return api.NewError(api.ErrRuntimeClosed, "iterator for store %q is closed", i.name)
+	}
+	return nil
+}
+
 func mapKVError(storeName string, kvErr kv.Error) error {
 	if kvErr.NotFound() {
 		return api.NewError(api.ErrStoreNotFound, "store %q key not found", storeName)
@@ -0,0 +1,155 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package intercept
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Recover turns a panic in the rest of the chain into an ErrDriverPanic
+// error carrying the panic value and stack. A panicking CheckHeartbeat
+// reports unhealthy.
+func Recover() Interceptor {
+	return func(call *Call, next Handler) (result Result) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = Result{Err: api.NewError(api.ErrDriverPanic, "%s panicked: %v\n%s", call.Method, r, debug.Stack())}
+			}
+		}()
+		return next(call)
+	}
+}
+
+// Logging writes one line per call to w with the call's arguments, as this
+// interceptor received them, and its duration and error.
+func Logging(w io.Writer) Interceptor {
+	var mu sync.Mutex
+	return func(call *Call, next Handler) Result {
+		args := describeArgs(call)
+		result := next(call)
+		line := fmt.Sprintf("fssdk: %s%s took %s", call.Method, args, result.Duration)
+		if call.Method == MethodCheckHeartbeat {
+			line += fmt.Sprintf(" healthy=%t", result.Healthy)
+		}
+		if result.Err != nil {
+			line += fmt.Sprintf(" error: %v", result.Err)
+		}
+		mu.Lock()
+		fmt.Fprintln(w, line)
+		mu.Unlock()
+		return result
+	}
+}
+
+func describeArgs(call *Call) string {
+	switch call.Method {
+	case MethodProcess:
+		return fmt.Sprintf(" source=%d bytes=%d", call.SourceID, len(call.Data))
+	case MethodProcessBatch:
+		return fmt.Sprintf(" source=%d records=%d", call.SourceID, len(call.Records))
+	case MethodProcessWatermark:
+		return fmt.Sprintf(" source=%d watermark=%d", call.SourceID, call.Watermark)
+	case MethodTakeCheckpoint:
+		return fmt.Sprintf(" checkpoint=%d", call.CheckpointID)
+	case MethodExec:
+		return fmt.Sprintf(" class=%s modules=%d", call.ClassName, len(call.Modules))
+	case MethodCustom:
+		return fmt.Sprintf(" bytes=%d", len(call.Data))
+	}
+	return ""
+}
+
+// Timing calls observe with the driver time of every call.
+func Timing(observe func(method Method, d time.Duration)) Interceptor {
+	return func(call *Call, next Handler) Result {
+		result := next(call)
+		observe(call.Method, result.Duration)
+		return result
+	}
+}
+
+// MethodStats aggregates the calls of one method.
+type MethodStats struct {
+	Calls  uint64
+	Errors uint64
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Metrics collects per-method call counts, error counts and driver time.
+// The zero value is ready to use and safe for concurrent use.
+type Metrics struct {
+	mu    sync.Mutex
+	stats map[Method]MethodStats
+}
+
+// Interceptor returns an interceptor that records into m.
+func (m *Metrics) Interceptor() Interceptor {
+	return func(call *Call, next Handler) Result {
+		result := next(call)
+		m.observe(call.Method, result)
+		return result
+	}
+}
+
+func (m *Metrics) observe(method Method, result Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stats == nil {
+		m.stats = make(map[Method]MethodStats)
+	}
+	stats := m.stats[method]
+	stats.Calls++
+	if result.Err != nil {
+		stats.Errors++
+	}
+	stats.Total += result.Duration
+	if result.Duration > stats.Max {
+		stats.Max = result.Duration
+	}
+	m.stats[method] = stats
+}
+
+// Stats returns a copy of the collected stats by method.
+func (m *Metrics) Stats() map[Method]MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[Method]MethodStats, len(m.stats))
+	for method, stats := range m.stats {
+		out[method] = stats
+	}
+	return out
+}
+
+// String formats the stats one method per line, sorted by method.
+func (m *Metrics) String() string {
+	stats := m.Stats()
+	methods := make([]string, 0, len(stats))
+	for method := range stats {
+		methods = append(methods, string(method))
+	}
+	sort.Strings(methods)
+	var out string
+	for _, method := range methods {
+		s := stats[Method(method)]
+		out += fmt.Sprintf("%s calls=%d errors=%d total=%s max=%s\n", method, s.Calls, s.Errors, s.Total, s.Max)
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package intercept wraps an api.Driver with interceptors that see every
+// lifecycle call, for logging, metrics, panic recovery and the like:
+//
+//	fssdk.Run(fssdk.Chain(&MyProcessor{},
+//		intercept.Recover(),
+//		intercept.Logging(os.Stderr),
+//	))
+//
+// The first interceptor is the outermost one.
+package intercept
+
+import (
+	"time"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Method names a Driver lifecycle method.
+type Method string
+
+const (
+	MethodInit             Method = "Init"
+	MethodProcess          Method = "Process"
+	MethodProcessBatch     Method = "ProcessBatch"
+	MethodProcessWatermark Method = "ProcessWatermark"
+	MethodTakeCheckpoint   Method = "TakeCheckpoint"
+	MethodCheckHeartbeat   Method = "CheckHeartbeat"
+	MethodClose            Method = "Close"
+	MethodExec             Method = "Exec"
+	MethodCustom           Method = "Custom"
+)
+
+// Call is one lifecycle call. Only the fields of Method are set; an
+// interceptor may change them before calling next.
+type Call struct {
+	Method Method
+	Ctx    api.Context
+
+	// Config is set for Init.
+	Config map[string]string
+	// SourceID is set for Process, ProcessBatch and ProcessWatermark.
+	SourceID uint32
+	// Data is the record for Process and the payload for Custom.
+	Data []byte
+	// Records is set for ProcessBatch.
+	Records [][]byte
+	// Watermark is set for ProcessWatermark.
+	Watermark uint64
+	// CheckpointID is set for TakeCheckpoint.
+	CheckpointID uint64
+	// ClassName and Modules are set for Exec.
+	ClassName string
+	Modules   []api.Module
+}
+
+// Result is the outcome of a call. Healthy is the CheckHeartbeat result and
+// Response the Custom result. Duration is the time spent in the driver; it
+// is zero when an interceptor short-circuits the call.
+type Result struct {
+	Err      error
+	Healthy  bool
+	Response []byte
+	Duration time.Duration
+}
+
+// Handler runs a call through the rest of the chain and the driver.
+type Handler func(call *Call) Result
+
+// Interceptor wraps a call. It calls next to continue, or returns its own
+// Result to short-circuit; it may change the call or the result.
+type Interceptor func(call *Call, next Handler) Result
+
+// Chain returns a driver that runs every call through interceptors, in order,
+// before driver. The result is an api.BatchProcessor only if driver is one,
+// so batch handling is unchanged. A nil driver yields nil.
+func Chain(driver api.Driver, interceptors ...Interceptor) api.Driver {
+	if driver == nil {
+		return nil
+	}
+	c := &chain{driver: driver}
+	c.handle = c.invoke
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], c.handle
+		c.handle = func(call *Call) Result {
+			return interceptor(call, next)
+		}
+	}
+	if _, ok := driver.(api.BatchProcessor); ok {
+		return &batchChain{chain: c}
+	}
+	return c
+}
+
+type chain struct {
+	driver api.Driver
+	handle Handler
+}
+
+var (
+	_ api.Driver         = (*chain)(nil)
+	_ api.BatchProcessor = (*batchChain)(nil)
+)
+
+// invoke calls the driver method named by call.
+func (c *chain) invoke(call *Call) (result Result) {
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+	switch call.Method {
+	case MethodInit:
+		result.Err = c.driver.Init(call.Ctx, call.Config)
+	case MethodProcess:
+		result.Err = c.driver.Process(call.Ctx, call.SourceID, call.Data)
+	case MethodProcessBatch:
+		result.Err = api.ProcessBatch(c.driver, call.Ctx, call.SourceID, call.Records)
+	case MethodProcessWatermark:
+		result.Err = c.driver.ProcessWatermark(call.Ctx, call.SourceID, call.Watermark)
+	case MethodTakeCheckpoint:
+		result.Err = c.driver.TakeCheckpoint(call.Ctx, call.CheckpointID)
+	case MethodCheckHeartbeat:
+		result.Healthy = c.driver.CheckHeartbeat(call.Ctx)
+	case MethodClose:
+		result.Err = c.driver.Close(call.Ctx)
+	case MethodExec:
+		result.Err = c.driver.Exec(call.Ctx, call.ClassName, call.Modules)
+	case MethodCustom:
+		result.Response, result.Err = c.driver.Custom(call.Ctx, call.Data)
+	default:
+		result.Err = api.NewError(api.ErrRuntimeInvalidDriver, "unknown driver method %q", call.Method)
+	}
+	return result
+}
+
+func (c *chain) Init(ctx api.Context, config map[string]string) error {
+	return c.handle(&Call{Method: MethodInit, Ctx: ctx, Config: config}).Err
+}
+
+func (c *chain) Process(ctx api.Context, sourceID uint32, data []byte) error {
+	return c.handle(&Call{Method: MethodProcess, Ctx: ctx, SourceID: sourceID, Data: data}).Err
+}
+
+func (c *chain) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64) error {
+	return c.handle(&Call{Method: MethodProcessWatermark, Ctx: ctx, SourceID: sourceID, Watermark: watermark}).Err
+}
+
+func (c *chain) TakeCheckpoint(ctx api.Context, checkpointID uint64) error {
+	return c.handle(&Call{Method: MethodTakeCheckpoint, Ctx: ctx, CheckpointID: checkpointID}).Err
+}
+
+func (c *chain) CheckHeartbeat(ctx api.Context) bool {
+	return c.handle(&Call{Method: MethodCheckHeartbeat, Ctx: ctx}).Healthy
+}
+
+func (c *chain) Close(ctx api.Context) error {
+	return c.handle(&Call{Method: MethodClose, Ctx: ctx}).Err
+}
+
+func (c *chain) Exec(ctx api.Context, className string, modules []api.Module) error {
+	return c.handle(&Call{Method: MethodExec, Ctx: ctx, ClassName: className, Modules: modules}).Err
+}
+
+func (c *chain) Custom(ctx api.Context, payload []byte) ([]byte, error) {
+	result := c.handle(&Call{Method: MethodCustom, Ctx: ctx, Data: payload})
+	return result.Response, result.Err
+}
+
+type batchChain struct {
+	*chain
+}
+
+func (c *batchChain) ProcessBatch(ctx api.Context, sourceID uint32, records [][]byte) error {
+	return c.handle(&Call{Method: MethodProcessBatch, Ctx: ctx, SourceID: sourceID, Records: records}).Err
+}
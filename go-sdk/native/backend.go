@@ -0,0 +1,128 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package native
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+)
+
+// backend is the impl.HostBackend for native runs: emits go to per-target
+// files and stores live in an in-memory backend.
+type backend struct {
+	stores *memory.Backend
+	out    *outputs
+}
+
+func (b *backend) Emit(targetID uint32, data []byte) error {
+	return b.out.emit(targetID, data)
+}
+
+func (b *backend) EmitWatermark(targetID uint32, watermark uint64) error {
+	return b.out.emitWatermark(targetID, watermark)
+}
+
+func (b *backend) OpenStore(name string) (api.Store, error) {
+	return b.stores.OpenStore(name), nil
+}
+
+// outputs routes emits to one record file per target ID and watermarks to
+// "target-<id>.watermarks" in the output directory. Files are created on first use.
+type outputs struct {
+	format Format
+	dir    string
+	paths  map[uint32]string
+	stdout io.Writer
+	files  map[string]*outputFile
+}
+
+type outputFile struct {
+	rw     *recordWriter
+	closer io.Closer
+}
+
+func newOutputs(format Format, dir string, paths map[uint32]string, stdout io.Writer) *outputs {
+	return &outputs{
+		format: format,
+		dir:    dir,
+		paths:  paths,
+		stdout: stdout,
+		files:  make(map[string]*outputFile),
+	}
+}
+
+func (o *outputs) emit(targetID uint32, data []byte) error {
+	file, err := o.open(o.dataPath(targetID), o.format)
+	if err != nil {
+		return err
+	}
+	return file.rw.write(data)
+}
+
+func (o *outputs) emitWatermark(targetID uint32, watermark uint64) error {
+	file, err := o.open(filepath.Join(o.dir, fmt.Sprintf("target-%d.watermarks", targetID)), FormatLines)
+	if err != nil {
+		return err
+	}
+	return file.rw.writeWatermark(watermark)
+}
+
+func (o *outputs) dataPath(targetID uint32) string {
+	if path, ok := o.paths[targetID]; ok {
+		return path
+	}
+	return filepath.Join(o.dir, fmt.Sprintf("target-%d.out", targetID))
+}
+
+func (o *outputs) open(path string, format Format) (*outputFile, error) {
+	if file, ok := o.files[path]; ok {
+		return file, nil
+	}
+	file := &outputFile{}
+	if path == "-" {
+		file.rw = newRecordWriter(o.stdout, format)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("create output directory: %w", err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("create output file: %w", err)
+		}
+		file.rw = newRecordWriter(f, format)
+		file.closer = f
+	}
+	o.files[path] = file
+	return file, nil
+}
+
+func (o *outputs) close() error {
+	var firstErr error
+	for _, file := range o.files {
+		if err := file.rw.flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if file.closer != nil {
+			if err := file.closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	o.files = make(map[string]*outputFile)
+	return firstErr
+}
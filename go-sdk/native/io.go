@@ -0,0 +1,119 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package native
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Format is the record framing used for input and output files.
+type Format string
+
+const (
+	// FormatLines frames one record per line; the trailing "\n" (and "\r") is stripped.
+	FormatLines Format = "lines"
+	// FormatLengthPrefixed frames each record with a 4-byte big-endian length.
+	FormatLengthPrefixed Format = "length"
+)
+
+func parseFormat(raw string) (Format, error) {
+	switch Format(raw) {
+	case FormatLines, FormatLengthPrefixed:
+		return Format(raw), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want %q or %q)", raw, FormatLines, FormatLengthPrefixed)
+	}
+}
+
+type recordReader struct {
+	format Format
+	r      *bufio.Reader
+}
+
+func newRecordReader(r io.Reader, format Format) *recordReader {
+	return &recordReader{format: format, r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// next returns the next record, or io.EOF when the input is exhausted.
+func (rr *recordReader) next() ([]byte, error) {
+	if rr.format == FormatLengthPrefixed {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(rr.r, lenBuf[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("truncated record length")
+			}
+			return nil, err
+		}
+		out := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+		if _, err := io.ReadFull(rr.r, out); err != nil {
+			return nil, fmt.Errorf("truncated record payload: %w", err)
+		}
+		return out, nil
+	}
+
+	line, err := rr.r.ReadBytes('\n')
+	if err == io.EOF && len(line) == 0 {
+		return nil, io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, nil
+}
+
+type recordWriter struct {
+	format Format
+	w      *bufio.Writer
+}
+
+func newRecordWriter(w io.Writer, format Format) *recordWriter {
+	return &recordWriter{format: format, w: bufio.NewWriter(w)}
+}
+
+func (rw *recordWriter) write(data []byte) error {
+	if rw.format == FormatLengthPrefixed {
+		if uint64(len(data)) > math.MaxUint32 {
+			return fmt.Errorf("record of %d bytes does not fit a 4-byte length", len(data))
+		}
+		var lenBuf [4]byte
+		binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+		if _, err := rw.w.Write(lenBuf[:]); err != nil {
+			return err
+		}
+		_, err := rw.w.Write(data)
+		return err
+	}
+	if _, err := rw.w.Write(data); err != nil {
+		return err
+	}
+	return rw.w.WriteByte('\n')
+}
+
+func (rw *recordWriter) writeWatermark(watermark uint64) error {
+	if _, err := rw.w.WriteString(strconv.FormatUint(watermark, 10)); err != nil {
+		return err
+	}
+	return rw.w.WriteByte('\n')
+}
+
+func (rw *recordWriter) flush() error {
+	return rw.w.Flush()
+}
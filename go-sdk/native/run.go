@@ -0,0 +1,361 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package native runs an api.Driver as an ordinary process instead of a WASM
+// component. Records are read from stdin or files, each target ID's emits are
+// written to its own file, and state lives in an in-memory store. Build the
+// processor with `-tags fsnative` to make fssdk.Run use this runner.
+package native
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/impl"
+	"github.com/functionstream/function-stream/go-sdk/state/memory"
+	"github.com/functionstream/function-stream/go-sdk/trace"
+)
+
+// Input maps a record file to a source ID. Path "-" reads stdin.
+type Input struct {
+	SourceID uint32
+	Path     string
+}
+
+// Options configures a native run.
+type Options struct {
+	// Config is passed to Driver.Init.
+	Config map[string]string
+	// Inputs are read round-robin, one record at a time. Empty means stdin as source 0.
+	Inputs []Input
+	// Outputs overrides the file for a target ID; "-" writes to Stdout.
+	Outputs map[uint32]string
+	// OutputDir holds "target-<id>.out" and "target-<id>.watermarks" files.
+	OutputDir string
+	// Format frames input and output records.
+	Format Format
+	// CheckpointEvery takes a checkpoint after every N records when positive.
+	CheckpointEvery int
+	// Record writes a trace of every export and host call to this file.
+	Record string
+	// Replay replays this trace against the driver instead of reading inputs.
+	Replay string
+	// CPUProfile and MemProfile write pprof profiles when set.
+	CPUProfile string
+	MemProfile string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Run parses the process arguments, runs driver to completion and exits the
+// process on failure. It is the native counterpart of impl.Run.
+func Run(driver api.Driver) {
+	opts, err := ParseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "fssdk native: %v\n", err)
+		os.Exit(2)
+	}
+	if err := Execute(driver, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "fssdk native: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// ParseFlags builds Options from command line arguments:
+//
+//	-config key=value     init config entry (repeatable)
+//	-input [id=]path      record file for a source ID, "-" for stdin (repeatable)
+//	-output id=path       record file for a target ID, "-" for stdout (repeatable)
+//	-out-dir dir          directory for per-target files (default "fs-output")
+//	-format lines|length  record framing (default "lines")
+//	-checkpoint-every n   take a checkpoint every n records
+//	-record file          write a trace of exports and host calls
+//	-replay file          replay a trace instead of reading inputs
+//	-cpuprofile file      write a CPU profile
+//	-memprofile file      write a heap profile on exit
+func ParseFlags(args []string) (Options, error) {
+	fs := flag.NewFlagSet("fssdk", flag.ContinueOnError)
+	config := kvFlag{}
+	inputs := &inputFlag{}
+	outputs := targetFlag{}
+	fs.Var(config, "config", "init config entry `key=value` (repeatable)")
+	fs.Var(inputs, "input", "record file `[source-id=]path` (repeatable, \"-\" for stdin)")
+	fs.Var(outputs, "output", "record file `target-id=path` (repeatable, \"-\" for stdout)")
+	outDir := fs.String("out-dir", "fs-output", "directory for per-target output files")
+	format := fs.String("format", string(FormatLines), "record framing: lines or length")
+	checkpointEvery := fs.Int("checkpoint-every", 0, "take a checkpoint every n records")
+	record := fs.String("record", "", "write a trace of exports and host calls to `file`")
+	replay := fs.String("replay", "", "replay the trace in `file` instead of reading inputs")
+	cpuProfile := fs.String("cpuprofile", "", "write a CPU profile to `file`")
+	memProfile := fs.String("memprofile", "", "write a heap profile to `file`")
+	if err := fs.Parse(args); err != nil {
+		return Options{}, err
+	}
+	if fs.NArg() > 0 {
+		return Options{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	parsedFormat, err := parseFormat(*format)
+	if err != nil {
+		return Options{}, err
+	}
+	return Options{
+		Config:          config,
+		Inputs:          inputs.items,
+		Outputs:         outputs,
+		OutputDir:       *outDir,
+		Format:          parsedFormat,
+		CheckpointEvery: *checkpointEvery,
+		Record:          *record,
+		Replay:          *replay,
+		CPUProfile:      *cpuProfile,
+		MemProfile:      *memProfile,
+	}, nil
+}
+
+// Execute runs the full lifecycle: init, process every input record, then close.
+func Execute(driver api.Driver, opts Options) (err error) {
+	if opts.Format == "" {
+		opts.Format = FormatLines
+	}
+	if opts.Stdin == nil {
+		opts.Stdin = os.Stdin
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if len(opts.Inputs) == 0 {
+		opts.Inputs = []Input{{SourceID: 0, Path: "-"}}
+	}
+
+	if opts.CPUProfile != "" {
+		f, err := os.Create(opts.CPUProfile)
+		if err != nil {
+			return fmt.Errorf("create cpu profile: %w", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("start cpu profile: %w", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	if opts.Replay != "" {
+		return trace.ReplayFile(driver, opts.Replay)
+	}
+	if opts.Record != "" {
+		f, createErr := os.Create(opts.Record)
+		if createErr != nil {
+			return fmt.Errorf("create trace: %w", createErr)
+		}
+		recorder, recErr := trace.NewRecorder(driver, f)
+		if recErr != nil {
+			f.Close()
+			return recErr
+		}
+		defer func() {
+			if recErr := recorder.Err(); recErr != nil && err == nil {
+				err = fmt.Errorf("write trace: %w", recErr)
+			}
+			if closeErr := f.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("close trace: %w", closeErr)
+			}
+		}()
+		driver = recorder.Driver()
+	}
+
+	out := newOutputs(opts.Format, opts.OutputDir, opts.Outputs, opts.Stdout)
+	defer func() {
+		if closeErr := out.close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close outputs: %w", closeErr)
+		}
+	}()
+
+	sources, closeInputs, err := openInputs(opts.Inputs, opts.Stdin, opts.Format)
+	if err != nil {
+		return err
+	}
+	defer closeInputs()
+
+	rt, err := impl.NewRuntime(driver, &backend{stores: memory.NewBackend(), out: out})
+	if err != nil {
+		return err
+	}
+	if err := rt.Init(opts.Config); err != nil {
+		return fmt.Errorf("init: %w", err)
+	}
+
+	var processed uint64
+	var checkpointID uint64
+	for len(sources) > 0 {
+		active := sources[:0]
+		for _, src := range sources {
+			record, readErr := src.reader.next()
+			if readErr == io.EOF {
+				continue
+			}
+			if readErr != nil {
+				return fmt.Errorf("read source %d (%s): %w", src.sourceID, src.path, readErr)
+			}
+			active = append(active, src)
+			if err := rt.Process(src.sourceID, record); err != nil {
+				return fmt.Errorf("process source %d record %d: %w", src.sourceID, src.records, err)
+			}
+			src.records++
+			processed++
+			if opts.CheckpointEvery > 0 && processed%uint64(opts.CheckpointEvery) == 0 {
+				checkpointID++
+				if err := rt.TakeCheckpoint(checkpointID); err != nil {
+					return fmt.Errorf("checkpoint %d: %w", checkpointID, err)
+				}
+			}
+		}
+		sources = active
+	}
+
+	if err := rt.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if opts.MemProfile != "" {
+		f, err := os.Create(opts.MemProfile)
+		if err != nil {
+			return fmt.Errorf("create heap profile: %w", err)
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("write heap profile: %w", err)
+		}
+	}
+	return nil
+}
+
+type source struct {
+	sourceID uint32
+	path     string
+	reader   *recordReader
+	records  uint64
+}
+
+func openInputs(inputs []Input, stdin io.Reader, format Format) ([]*source, func(), error) {
+	closers := make([]io.Closer, 0, len(inputs))
+	closeAll := func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+	out := make([]*source, 0, len(inputs))
+	for i, in := range inputs {
+		if err := checkInput(inputs[:i], in); err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		var r io.Reader
+		if in.Path == "-" {
+			r = stdin
+		} else {
+			f, err := os.Open(in.Path)
+			if err != nil {
+				closeAll()
+				return nil, nil, fmt.Errorf("open input for source %d: %w", in.SourceID, err)
+			}
+			closers = append(closers, f)
+			r = f
+		}
+		out = append(out, &source{sourceID: in.SourceID, path: in.Path, reader: newRecordReader(r, format)})
+	}
+	return out, closeAll, nil
+}
+
+type kvFlag map[string]string
+
+func (f kvFlag) String() string {
+	return ""
+}
+
+func (f kvFlag) Set(raw string) error {
+	k, v, ok := strings.Cut(raw, "=")
+	if !ok {
+		return fmt.Errorf("%q must be key=value", raw)
+	}
+	f[k] = v
+	return nil
+}
+
+type inputFlag struct {
+	items []Input
+}
+
+func (f *inputFlag) String() string {
+	return ""
+}
+
+func (f *inputFlag) Set(raw string) error {
+	in := Input{SourceID: uint32(len(f.items)), Path: raw}
+	if idRaw, path, ok := strings.Cut(raw, "="); ok {
+		id, err := strconv.ParseUint(idRaw, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid source id %q", idRaw)
+		}
+		in = Input{SourceID: uint32(id), Path: path}
+	}
+	if err := checkInput(f.items, in); err != nil {
+		return err
+	}
+	f.items = append(f.items, in)
+	return nil
+}
+
+// checkInput rejects an input whose source ID is already taken, and a second
+// input reading stdin.
+func checkInput(existing []Input, in Input) error {
+	for _, prev := range existing {
+		if prev.SourceID == in.SourceID {
+			return fmt.Errorf("source id %d is used by more than one input", in.SourceID)
+		}
+		if prev.Path == "-" && in.Path == "-" {
+			return errors.New("stdin can be used by only one input")
+		}
+	}
+	return nil
+}
+
+type targetFlag map[uint32]string
+
+func (f targetFlag) String() string {
+	return ""
+}
+
+func (f targetFlag) Set(raw string) error {
+	idRaw, path, ok := strings.Cut(raw, "=")
+	if !ok {
+		return fmt.Errorf("%q must be target-id=path", raw)
+	}
+	id, err := strconv.ParseUint(idRaw, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid target id %q", idRaw)
+	}
+	f[uint32(id)] = path
+	return nil
+}
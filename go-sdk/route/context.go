@@ -0,0 +1,68 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Context is an api.Context with named outputs.
+type Context struct {
+	api.Context
+	router *Router
+}
+
+// Output returns the named output. Emitting to a name that is not mapped
+// fails with ErrRuntimeInvalidConfig; declare names with Router.Outputs to
+// catch them at Init instead.
+func (c *Context) Output(name string) Output {
+	id, ok := c.router.outputs[name]
+	return Output{ctx: c.Context, name: name, id: id, mapped: ok}
+}
+
+// Flush sends the emits the wrapped context has buffered, including those
+// made through Output.Emit, so handlers can call api.Flush on a route Context.
+func (c *Context) Flush() error {
+	return api.Flush(c.Context)
+}
+
+// Output emits to one named target.
+type Output struct {
+	ctx    api.Context
+	name   string
+	id     uint32
+	mapped bool
+}
+
+// ID returns the target ID the name is mapped to.
+func (o Output) ID() uint32 {
+	return o.id
+}
+
+func (o Output) Emit(data []byte) error {
+	if !o.mapped {
+		return o.unmapped()
+	}
+	return o.ctx.Emit(o.id, data)
+}
+
+func (o Output) EmitWatermark(watermark uint64) error {
+	if !o.mapped {
+		return o.unmapped()
+	}
+	return o.ctx.EmitWatermark(o.id, watermark)
+}
+
+func (o Output) unmapped() error {
+	return api.NewError(api.ErrRuntimeInvalidConfig, "output %q is not mapped; set %s%s", o.name, ConfigOutputPrefix, o.name)
+}
@@ -0,0 +1,211 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package route addresses inputs and outputs by name instead of by position.
+// The init config maps names to IDs, matching the order of input-groups and
+// outputs in config.yaml:
+//
+//	init:
+//	  input.clicks: "0"
+//	  output.alerts: "1"
+//
+// A Router dispatches records to per-source handlers, and handlers emit
+// through named outputs:
+//
+//	r := route.NewRouter().Outputs("alerts")
+//	r.OnSource("clicks", func(ctx *route.Context, data []byte) error {
+//		return ctx.Output("alerts").Emit(data)
+//	})
+//	fssdk.Run(r)
+//
+// Names that are used but not mapped fail Init.
+package route
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Config key prefixes for the name-to-ID mapping.
+const (
+	ConfigInputPrefix  = "input."
+	ConfigOutputPrefix = "output."
+)
+
+// Handler processes a record from a named source.
+type Handler func(ctx *Context, data []byte) error
+
+// WatermarkHandler processes a watermark from a named source.
+type WatermarkHandler func(ctx *Context, watermark uint64) error
+
+// Router is an api.Driver that resolves the name mapping in Init and
+// dispatches Process and ProcessWatermark by source name. Register handlers
+// and outputs before Init.
+type Router struct {
+	api.BaseDriver
+
+	outputNames []string
+	records     map[string]Handler
+	watermarks  map[string]WatermarkHandler
+	inits       []func(ctx *Context, config map[string]string) error
+
+	inputs  map[string]uint32
+	outputs map[string]uint32
+	sources map[uint32]string
+}
+
+// NewRouter creates an empty router.
+func NewRouter() *Router {
+	return &Router{records: make(map[string]Handler), watermarks: make(map[string]WatermarkHandler)}
+}
+
+// OnSource registers the record handler for the named input.
+func (r *Router) OnSource(name string, handler Handler) *Router {
+	r.records[name] = handler
+	return r
+}
+
+// OnWatermark registers the watermark handler for the named input. Watermarks
+// from inputs without one are ignored.
+func (r *Router) OnWatermark(name string, handler WatermarkHandler) *Router {
+	r.watermarks[name] = handler
+	return r
+}
+
+// Outputs declares output names that must be mapped for Init to succeed.
+func (r *Router) Outputs(names ...string) *Router {
+	r.outputNames = append(r.outputNames, names...)
+	return r
+}
+
+// OnInit registers fn to run at the end of Init, after the mapping is
+// resolved.
+func (r *Router) OnInit(fn func(ctx *Context, config map[string]string) error) *Router {
+	r.inits = append(r.inits, fn)
+	return r
+}
+
+// Init resolves the mapping. Invalid IDs, names mapped to the same ID, and
+// handlers or declared outputs without a mapping are reported together in one
+// ErrRuntimeInvalidConfig error.
+func (r *Router) Init(ctx api.Context, config map[string]string) error {
+	var problems []string
+	inputs := parseMapping(config, ConfigInputPrefix, &problems)
+	outputs := parseMapping(config, ConfigOutputPrefix, &problems)
+	for _, name := range sortedNames(r.records) {
+		if _, ok := inputs[name]; !ok {
+			problems = append(problems, "input "+strconv.Quote(name)+" has a handler but no "+ConfigInputPrefix+name+" key")
+		}
+	}
+	for _, name := range sortedNames(r.watermarks) {
+		if _, ok := inputs[name]; !ok {
+			problems = append(problems, "input "+strconv.Quote(name)+" has a watermark handler but no "+ConfigInputPrefix+name+" key")
+		}
+	}
+	for _, name := range r.outputNames {
+		if _, ok := outputs[name]; !ok {
+			problems = append(problems, "output "+strconv.Quote(name)+" has no "+ConfigOutputPrefix+name+" key")
+		}
+	}
+	if len(problems) > 0 {
+		return api.NewError(api.ErrRuntimeInvalidConfig, "invalid routes: %s", strings.Join(problems, "; "))
+	}
+
+	r.inputs, r.outputs = inputs, outputs
+	r.sources = make(map[uint32]string, len(inputs))
+	for name, id := range inputs {
+		r.sources[id] = name
+	}
+	named := r.Context(ctx)
+	for _, fn := range r.inits {
+		if err := fn(named, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Process calls the handler of the record's source. A record from an
+// unmapped source, or from one without a handler, fails.
+func (r *Router) Process(ctx api.Context, sourceID uint32, data []byte) error {
+	name, ok := r.sources[sourceID]
+	if !ok {
+		return api.NewError(api.ErrRuntimeInvalidConfig, "source %d is not mapped to an input name", sourceID)
+	}
+	handler, ok := r.records[name]
+	if !ok {
+		return api.NewError(api.ErrRuntimeInvalidConfig, "input %q has no handler", name)
+	}
+	return handler(r.Context(ctx), data)
+}
+
+func (r *Router) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64) error {
+	handler, ok := r.watermarks[r.sources[sourceID]]
+	if !ok {
+		return nil
+	}
+	return handler(r.Context(ctx), watermark)
+}
+
+// Context wraps ctx with the router's named outputs, for use outside
+// handlers.
+func (r *Router) Context(ctx api.Context) *Context {
+	return &Context{Context: ctx, router: r}
+}
+
+// InputID returns the ID mapped to the named input.
+func (r *Router) InputID(name string) (uint32, bool) {
+	id, ok := r.inputs[name]
+	return id, ok
+}
+
+// Source returns the input name mapped to sourceID.
+func (r *Router) Source(sourceID uint32) (string, bool) {
+	name, ok := r.sources[sourceID]
+	return name, ok
+}
+
+func parseMapping(config map[string]string, prefix string, problems *[]string) map[string]uint32 {
+	out := make(map[string]uint32)
+	owners := make(map[uint32]string)
+	for _, key := range sortedNames(config) {
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		raw := strings.TrimSpace(config[key])
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if name == "" || err != nil {
+			*problems = append(*problems, key+": invalid ID "+strconv.Quote(raw))
+			continue
+		}
+		if owner, dup := owners[uint32(id)]; dup {
+			*problems = append(*problems, key+": ID "+raw+" is already mapped to "+strconv.Quote(owner))
+			continue
+		}
+		owners[uint32(id)] = name
+		out[name] = uint32(id)
+	}
+	return out
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
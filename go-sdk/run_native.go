@@ -0,0 +1,23 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build fsnative
+
+package fssdk
+
+import "github.com/functionstream/function-stream/go-sdk/native"
+
+// Run executes the driver as a native process reading records from stdin or
+// files (see package native). Selected with the fsnative build tag.
+func Run(driver Driver) {
+	native.Run(driver)
+}
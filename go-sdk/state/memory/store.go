@@ -0,0 +1,514 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memory provides a pure-Go, in-memory api.Store with the same
+// semantics as the host KV store. It lets drivers and state types run under
+// plain `go test` without a WASM host.
+package memory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+	"github.com/functionstream/function-stream/go-sdk/state/common"
+)
+
+// Backend holds named in-memory tables. Stores opened with the same name share
+// data, the same way a store reopened on the host after fs-init sees the
+// previous contents.
+type Backend struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+// NewBackend creates an empty in-memory backend.
+func NewBackend() *Backend {
+	return &Backend{tables: make(map[string]*table)}
+}
+
+// OpenStore returns a new handle on the table called name, creating it if needed.
+func (b *Backend) OpenStore(name string) *Store {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tables[name]
+	if !ok {
+		t = newTable()
+		b.tables[name] = t
+	}
+	return &Store{name: name, data: t}
+}
+
+// StoreNames returns the names of all tables in the backend, sorted.
+func (b *Backend) StoreNames() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.tables))
+	for name := range b.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Snapshot is a point-in-time copy of every table in a Backend.
+type Snapshot struct {
+	tables map[string]*table
+}
+
+// Snapshot copies the contents of every table.
+func (b *Backend) Snapshot() *Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snap := &Snapshot{tables: make(map[string]*table, len(b.tables))}
+	for name, t := range b.tables {
+		snap.tables[name] = t.clone()
+	}
+	return snap
+}
+
+// Restore replaces the contents of every table with the snapshot. Tables are
+// updated in place, so store handles opened earlier see the restored data;
+// tables missing from the snapshot are emptied.
+func (b *Backend) Restore(snap *Snapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, t := range b.tables {
+		if _, ok := snap.tables[name]; !ok {
+			t.replace(newTable())
+		}
+	}
+	for name, saved := range snap.tables {
+		t, ok := b.tables[name]
+		if !ok {
+			t = newTable()
+			b.tables[name] = t
+		}
+		t.replace(saved)
+	}
+}
+
+// NewBackendFromSnapshot creates a backend holding a copy of snap.
+func NewBackendFromSnapshot(snap *Snapshot) *Backend {
+	b := NewBackend()
+	b.Restore(snap)
+	return b
+}
+
+// Diff describes how other differs from s, one line per differing entry, in
+// a stable order. Empty tables are treated as missing. An empty result means
+// the snapshots hold the same data.
+func (s *Snapshot) Diff(other *Snapshot) []string {
+	names := make(map[string]struct{})
+	for name := range s.tables {
+		names[name] = struct{}{}
+	}
+	for name := range other.tables {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	var out []string
+	for _, name := range sorted {
+		a, b := s.tables[name], other.tables[name]
+		if a == nil {
+			a = newTable()
+		}
+		if b == nil {
+			b = newTable()
+		}
+		out = append(out, diffStates(name, a.states, b.states)...)
+		out = append(out, diffComplex(name, a.complex, b.complex)...)
+	}
+	return out
+}
+
+func diffStates(store string, a, b map[string][]byte) []string {
+	var out []string
+	for _, k := range unionKeys(a, b) {
+		va, inA := a[k]
+		vb, inB := b[k]
+		if inA != inB || !bytes.Equal(va, vb) {
+			out = append(out, fmt.Sprintf("store %q state %q: %s != %s", store, k, describeValue(va, inA), describeValue(vb, inB)))
+		}
+	}
+	return out
+}
+
+func diffComplex(store string, a, b map[string]complexEntry) []string {
+	var out []string
+	for _, k := range unionKeys(a, b) {
+		ea, inA := a[k]
+		eb, inB := b[k]
+		if inA != inB || !bytes.Equal(ea.value, eb.value) {
+			key := ea.key
+			if !inA {
+				key = eb.key
+			}
+			out = append(out, fmt.Sprintf("store %q key (%q, %q, %q, %q): %s != %s", store,
+				key.KeyGroup, key.Key, key.Namespace, key.UserKey, describeValue(ea.value, inA), describeValue(eb.value, inB)))
+		}
+	}
+	return out
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func describeValue(value []byte, found bool) string {
+	if !found {
+		return "<missing>"
+	}
+	return strconv.Quote(string(value))
+}
+
+// Store is an in-memory api.Store. Complex keys are ordered by
+// (KeyGroup, Key, Namespace, UserKey); Merge appends to the existing value.
+type Store struct {
+	name   string
+	data   *table
+	closed atomic.Bool
+}
+
+var _ api.Store = (*Store)(nil)
+
+// NewStore creates a standalone store backed by its own table.
+func NewStore(name string) *Store {
+	return &Store{name: name, data: newTable()}
+}
+
+// Name returns the store name.
+func (s *Store) Name() string {
+	return s.name
+}
+
+func (s *Store) PutState(key []byte, value []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.states[string(key)] = cloneValue(value)
+	return nil
+}
+
+func (s *Store) GetState(key []byte) ([]byte, bool, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, false, err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	val, ok := s.data.states[string(key)]
+	if !ok {
+		return nil, false, nil
+	}
+	return cloneValue(val), true, nil
+}
+
+func (s *Store) DeleteState(key []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	delete(s.data.states, string(key))
+	return nil
+}
+
+func (s *Store) ListStates(startInclusive []byte, endExclusive []byte) ([][]byte, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	out := make([][]byte, 0)
+	for k := range s.data.states {
+		key := []byte(k)
+		if bytes.Compare(key, startInclusive) >= 0 && bytes.Compare(key, endExclusive) < 0 {
+			out = append(out, key)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return bytes.Compare(out[i], out[j]) < 0
+	})
+	return out, nil
+}
+
+func (s *Store) Put(key api.ComplexKey, value []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.complex[encodeComplexKey(key)] = complexEntry{key: cloneComplexKey(key), value: cloneValue(value)}
+	return nil
+}
+
+func (s *Store) Get(key api.ComplexKey) ([]byte, bool, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, false, err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	entry, ok := s.data.complex[encodeComplexKey(key)]
+	if !ok {
+		return nil, false, nil
+	}
+	return cloneValue(entry.value), true, nil
+}
+
+func (s *Store) Delete(key api.ComplexKey) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	delete(s.data.complex, encodeComplexKey(key))
+	return nil
+}
+
+// Merge appends value to the existing value, as the host's kv.store merge
+// does through its state backend. ListState.Add and KeyedListState.Add rely
+// on the append.
+func (s *Store) Merge(key api.ComplexKey, value []byte) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	encoded := encodeComplexKey(key)
+	entry, ok := s.data.complex[encoded]
+	if !ok {
+		s.data.complex[encoded] = complexEntry{key: cloneComplexKey(key), value: cloneValue(value)}
+		return nil
+	}
+	merged := make([]byte, 0, len(entry.value)+len(value))
+	merged = append(merged, entry.value...)
+	merged = append(merged, value...)
+	entry.value = merged
+	s.data.complex[encoded] = entry
+	return nil
+}
+
+// DeletePrefix removes every entry under (KeyGroup, Key, Namespace); UserKey is ignored.
+func (s *Store) DeletePrefix(key api.ComplexKey) error {
+	if err := s.checkOpen(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for encoded, entry := range s.data.complex {
+		if samePrefix(entry.key, key.KeyGroup, key.Key, key.Namespace) {
+			delete(s.data.complex, encoded)
+		}
+	}
+	return nil
+}
+
+// ListComplex returns the user keys under (keyGroup, key, namespace) in
+// [startInclusive, endExclusive), in byte order.
+func (s *Store) ListComplex(
+	keyGroup []byte,
+	key []byte,
+	namespace []byte,
+	startInclusive []byte,
+	endExclusive []byte,
+) ([][]byte, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	entries := s.data.scan(keyGroup, key, namespace)
+	out := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		userKey := entry.key.UserKey
+		if bytes.Compare(userKey, startInclusive) >= 0 && bytes.Compare(userKey, endExclusive) < 0 {
+			out = append(out, cloneValue(userKey))
+		}
+	}
+	return out, nil
+}
+
+// ScanComplex returns an iterator over a snapshot of the (user key, value)
+// pairs under (keyGroup, key, namespace), in user key byte order.
+func (s *Store) ScanComplex(keyGroup []byte, key []byte, namespace []byte) (api.Iterator, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	return &iterator{name: s.name, entries: s.data.scan(keyGroup, key, namespace)}, nil
+}
+
+func (s *Store) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func (s *Store) checkOpen() error {
+	if s.closed.Load() {
+		return api.NewError(api.ErrRuntimeClosed, "store %q is closed", s.name)
+	}
+	return nil
+}
+
+type iterator struct {
+	name    string
+	entries []complexEntry
+	pos     int
+	closed  bool
+}
+
+func (i *iterator) HasNext() (bool, error) {
+	if i.closed {
+		return false, api.NewError(api.ErrRuntimeClosed, "iterator for store %q is closed", i.name)
+	}
+	return i.pos < len(i.entries), nil
+}
+
+func (i *iterator) Next() ([]byte, []byte, bool, error) {
+	if i.closed {
+		return nil, nil, false, api.NewError(api.ErrRuntimeClosed, "iterator for store %q is closed", i.name)
+	}
+	if i.pos >= len(i.entries) {
+		return nil, nil, false, nil
+	}
+	entry := i.entries[i.pos]
+	i.pos++
+	return cloneValue(entry.key.UserKey), cloneValue(entry.value), true, nil
+}
+
+func (i *iterator) Close() error {
+	i.closed = true
+	i.entries = nil
+	return nil
+}
+
+type complexEntry struct {
+	key   api.ComplexKey
+	value []byte
+}
+
+type table struct {
+	mu      sync.Mutex
+	states  map[string][]byte
+	complex map[string]complexEntry
+}
+
+func newTable() *table {
+	return &table{
+		states:  make(map[string][]byte),
+		complex: make(map[string]complexEntry),
+	}
+}
+
+func (t *table) clone() *table {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := newTable()
+	for k, v := range t.states {
+		out.states[k] = cloneValue(v)
+	}
+	for k, entry := range t.complex {
+		out.complex[k] = complexEntry{key: cloneComplexKey(entry.key), value: cloneValue(entry.value)}
+	}
+	return out
+}
+
+// replace swaps in a copy of src's contents.
+func (t *table) replace(src *table) {
+	copied := src.clone()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states = copied.states
+	t.complex = copied.complex
+}
+
+func (t *table) scan(keyGroup []byte, key []byte, namespace []byte) []complexEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]complexEntry, 0)
+	for _, entry := range t.complex {
+		if samePrefix(entry.key, keyGroup, key, namespace) {
+			out = append(out, complexEntry{key: cloneComplexKey(entry.key), value: cloneValue(entry.value)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return compareComplexKey(out[i].key, out[j].key) < 0
+	})
+	return out
+}
+
+func samePrefix(ck api.ComplexKey, keyGroup []byte, key []byte, namespace []byte) bool {
+	return bytes.Equal(ck.KeyGroup, keyGroup) && bytes.Equal(ck.Key, key) && bytes.Equal(ck.Namespace, namespace)
+}
+
+func compareComplexKey(a api.ComplexKey, b api.ComplexKey) int {
+	if c := bytes.Compare(a.KeyGroup, b.KeyGroup); c != 0 {
+		return c
+	}
+	if c := bytes.Compare(a.Key, b.Key); c != 0 {
+		return c
+	}
+	if c := bytes.Compare(a.Namespace, b.Namespace); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.UserKey, b.UserKey)
+}
+
+// encodeComplexKey builds an unambiguous map key from the four key parts.
+func encodeComplexKey(ck api.ComplexKey) string {
+	parts := [][]byte{ck.KeyGroup, ck.Key, ck.Namespace, ck.UserKey}
+	size := 0
+	for _, part := range parts {
+		size += binary.MaxVarintLen64 + len(part)
+	}
+	out := make([]byte, 0, size)
+	for _, part := range parts {
+		out = binary.AppendUvarint(out, uint64(len(part)))
+		out = append(out, part...)
+	}
+	return string(out)
+}
+
+func cloneComplexKey(ck api.ComplexKey) api.ComplexKey {
+	return api.ComplexKey{
+		KeyGroup:  cloneValue(ck.KeyGroup),
+		Key:       cloneValue(ck.Key),
+		Namespace: cloneValue(ck.Namespace),
+		UserKey:   cloneValue(ck.UserKey),
+	}
+}
+
+// cloneValue copies input into a non-nil slice so found values are never nil.
+func cloneValue(input []byte) []byte {
+	if input == nil {
+		return []byte{}
+	}
+	return common.DupBytes(input)
+}
@@ -0,0 +1,532 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storetest checks an api.Store implementation against the semantics
+// of the host KV store. Wrapper stores (caches, encryption, metrics) and
+// alternative backends call RunStoreConformance from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.RunStoreConformance(t, func() api.Store {
+//			return mystore.Wrap(memory.NewStore("conformance"))
+//		})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// RunStoreConformance runs every check as a subtest. newStore must return a
+// fresh, empty, open store on each call; the suite closes it.
+func RunStoreConformance(t *testing.T, newStore func() api.Store) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := newStore()
+			if store == nil {
+				t.Fatal("newStore returned nil")
+			}
+			defer store.Close()
+			c.run(t, store)
+		})
+	}
+}
+
+// The host builds complex keys by concatenating the four parts into the same
+// keyspace as PutState, so the cases only rely on parts that stay distinct
+// after concatenation.
+type conformanceCase struct {
+	name string
+	run  func(t *testing.T, s api.Store)
+}
+
+var cases = []conformanceCase{
+	{"State/GetMissing", testStateGetMissing},
+	{"State/EmptyValue", testStateEmptyValue},
+	{"State/Overwrite", testStateOverwrite},
+	{"State/Delete", testStateDelete},
+	{"State/ValuesAreCopied", testStateValuesAreCopied},
+	{"State/ListHalfOpen", testStateListHalfOpen},
+	{"State/ListEmptyRange", testStateListEmptyRange},
+	{"Complex/GetMissing", testComplexGetMissing},
+	{"Complex/EmptyValue", testComplexEmptyValue},
+	{"Complex/KeyPartsAreDistinct", testComplexKeyPartsAreDistinct},
+	{"Complex/NilAndEmptyPartsMatch", testComplexNilAndEmptyPartsMatch},
+	{"Complex/Delete", testComplexDelete},
+	{"Complex/ValuesAreCopied", testComplexValuesAreCopied},
+	{"Merge/Missing", testMergeMissing},
+	{"Merge/Appends", testMergeAppends},
+	{"DeletePrefix/ScopedByNamespace", testDeletePrefixScopedByNamespace},
+	{"DeletePrefix/IgnoresUserKey", testDeletePrefixIgnoresUserKey},
+	{"ListComplex/Bounds", testListComplexBounds},
+	{"ListComplex/ScopedByPrefix", testListComplexScopedByPrefix},
+	{"Scan/Ordered", testScanOrdered},
+	{"Scan/Empty", testScanEmpty},
+	{"Scan/Exhaustion", testScanExhaustion},
+	{"Scan/CloseTwice", testScanCloseTwice},
+	{"Scan/AfterClose", testScanAfterClose},
+	{"Close/Twice", testCloseTwice},
+	{"Close/OperationsFail", testCloseOperationsFail},
+}
+
+func testStateGetMissing(t *testing.T, s api.Store) {
+	val, found, err := s.GetState([]byte("missing"))
+	if err != nil {
+		t.Fatalf("GetState(missing) error: %v", err)
+	}
+	if found || len(val) != 0 {
+		t.Fatalf("GetState(missing) = %q, %t; want not found", val, found)
+	}
+}
+
+func testStateEmptyValue(t *testing.T, s api.Store) {
+	mustPutState(t, s, "k", "")
+	val, found := mustGetState(t, s, "k")
+	if !found {
+		t.Fatal("GetState after PutState with an empty value: not found; an empty value must be distinct from a missing key")
+	}
+	if len(val) != 0 {
+		t.Fatalf("GetState = %q, want empty", val)
+	}
+}
+
+func testStateOverwrite(t *testing.T, s api.Store) {
+	mustPutState(t, s, "k", "v1")
+	mustPutState(t, s, "k", "v2")
+	wantState(t, s, "k", "v2")
+}
+
+func testStateDelete(t *testing.T, s api.Store) {
+	mustPutState(t, s, "k", "v")
+	if err := s.DeleteState([]byte("k")); err != nil {
+		t.Fatalf("DeleteState error: %v", err)
+	}
+	wantNoState(t, s, "k")
+	if err := s.DeleteState([]byte("missing")); err != nil {
+		t.Fatalf("DeleteState(missing) error: %v", err)
+	}
+}
+
+func testStateValuesAreCopied(t *testing.T, s api.Store) {
+	key := []byte("k")
+	value := []byte("value")
+	if err := s.PutState(key, value); err != nil {
+		t.Fatalf("PutState error: %v", err)
+	}
+	key[0] = 'x'
+	value[0] = 'X'
+	got, _ := mustGetState(t, s, "k")
+	if string(got) != "value" {
+		t.Fatalf("GetState = %q after mutating the PutState arguments, want %q", got, "value")
+	}
+	got[0] = 'X'
+	wantState(t, s, "k", "value")
+}
+
+func testStateListHalfOpen(t *testing.T, s api.Store) {
+	for _, k := range []string{"d", "a", "c", "b", "e"} {
+		mustPutState(t, s, k, "v"+k)
+	}
+	wantKeys(t, "ListStates(b, d)", mustListStates(t, s, "b", "d"), "b", "c")
+	wantKeys(t, "ListStates(a, f)", mustListStates(t, s, "a", "f"), "a", "b", "c", "d", "e")
+	wantKeys(t, "ListStates(c, c\\x00)", mustListStates(t, s, "c", "c\x00"), "c")
+}
+
+func testStateListEmptyRange(t *testing.T, s api.Store) {
+	mustPutState(t, s, "a", "1")
+	mustPutState(t, s, "b", "2")
+	wantKeys(t, "ListStates(b, b)", mustListStates(t, s, "b", "b"))
+	wantKeys(t, "ListStates(c, z)", mustListStates(t, s, "c", "z"))
+}
+
+func testComplexGetMissing(t *testing.T, s api.Store) {
+	val, found, err := s.Get(ck("g", "k", "n", "u"))
+	if err != nil {
+		t.Fatalf("Get(missing) error: %v", err)
+	}
+	if found || len(val) != 0 {
+		t.Fatalf("Get(missing) = %q, %t; want not found", val, found)
+	}
+}
+
+func testComplexEmptyValue(t *testing.T, s api.Store) {
+	key := ck("g", "k", "n", "u")
+	mustPut(t, s, key, "")
+	val, found := mustGet(t, s, key)
+	if !found {
+		t.Fatal("Get after Put with an empty value: not found; an empty value must be distinct from a missing key")
+	}
+	if len(val) != 0 {
+		t.Fatalf("Get = %q, want empty", val)
+	}
+}
+
+func testComplexKeyPartsAreDistinct(t *testing.T, s api.Store) {
+	base := ck("g", "k", "n", "u")
+	mustPut(t, s, base, "base")
+	for _, other := range []api.ComplexKey{
+		ck("g2", "k", "n", "u"),
+		ck("g", "k2", "n", "u"),
+		ck("g", "k", "n2", "u"),
+		ck("g", "k", "n", "u2"),
+	} {
+		if _, found := mustGet(t, s, other); found {
+			t.Fatalf("Get(%s) found a value written under %s", describe(other), describe(base))
+		}
+	}
+	want(t, s, base, "base")
+}
+
+func testComplexNilAndEmptyPartsMatch(t *testing.T, s api.Store) {
+	mustPut(t, s, api.ComplexKey{Key: []byte("k")}, "v")
+	want(t, s, api.ComplexKey{KeyGroup: []byte{}, Key: []byte("k"), Namespace: []byte{}, UserKey: []byte{}}, "v")
+}
+
+func testComplexDelete(t *testing.T, s api.Store) {
+	key := ck("g", "k", "n", "u")
+	mustPut(t, s, key, "v")
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, found := mustGet(t, s, key); found {
+		t.Fatal("Get after Delete: found")
+	}
+	if err := s.Delete(ck("g", "k", "n", "missing")); err != nil {
+		t.Fatalf("Delete(missing) error: %v", err)
+	}
+}
+
+func testComplexValuesAreCopied(t *testing.T, s api.Store) {
+	key := ck("g", "k", "n", "u")
+	value := []byte("value")
+	if err := s.Put(key, value); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	key.UserKey[0] = 'x'
+	value[0] = 'X'
+	got, _ := mustGet(t, s, ck("g", "k", "n", "u"))
+	if string(got) != "value" {
+		t.Fatalf("Get = %q after mutating the Put arguments, want %q", got, "value")
+	}
+	got[0] = 'X'
+	want(t, s, ck("g", "k", "n", "u"), "value")
+}
+
+func testMergeMissing(t *testing.T, s api.Store) {
+	key := ck("g", "k", "n", "u")
+	if err := s.Merge(key, []byte("a")); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	want(t, s, key, "a")
+}
+
+func testMergeAppends(t *testing.T, s api.Store) {
+	key := ck("g", "k", "n", "u")
+	mustPut(t, s, key, "a")
+	for _, part := range []string{"b", "c"} {
+		if err := s.Merge(key, []byte(part)); err != nil {
+			t.Fatalf("Merge error: %v", err)
+		}
+	}
+	want(t, s, key, "abc")
+}
+
+func testDeletePrefixScopedByNamespace(t *testing.T, s api.Store) {
+	mustPut(t, s, ck("g", "k", "n1", "a"), "1")
+	mustPut(t, s, ck("g", "k", "n1", "b"), "2")
+	mustPut(t, s, ck("g", "k", "n2", "a"), "3")
+	mustPut(t, s, ck("g", "k2", "n1", "a"), "4")
+	mustPut(t, s, ck("g2", "k", "n1", "a"), "5")
+	mustPutState(t, s, "a", "6")
+	if err := s.DeletePrefix(ck("g", "k", "n1", "")); err != nil {
+		t.Fatalf("DeletePrefix error: %v", err)
+	}
+	wantKeys(t, "ListComplex(g, k, n1)", mustListComplex(t, s, "g", "k", "n1", "", "\xff"))
+	want(t, s, ck("g", "k", "n2", "a"), "3")
+	want(t, s, ck("g", "k2", "n1", "a"), "4")
+	want(t, s, ck("g2", "k", "n1", "a"), "5")
+	wantState(t, s, "a", "6")
+}
+
+func testDeletePrefixIgnoresUserKey(t *testing.T, s api.Store) {
+	mustPut(t, s, ck("g", "k", "n", "a"), "1")
+	mustPut(t, s, ck("g", "k", "n", "b"), "2")
+	if err := s.DeletePrefix(ck("g", "k", "n", "a")); err != nil {
+		t.Fatalf("DeletePrefix error: %v", err)
+	}
+	wantKeys(t, "ListComplex(g, k, n)", mustListComplex(t, s, "g", "k", "n", "", "\xff"))
+}
+
+func testListComplexBounds(t *testing.T, s api.Store) {
+	for _, u := range []string{"d", "a", "c", "b", "e"} {
+		mustPut(t, s, ck("g", "k", "n", u), "v"+u)
+	}
+	wantKeys(t, "ListComplex(b, d)", mustListComplex(t, s, "g", "k", "n", "b", "d"), "b", "c")
+	wantKeys(t, "ListComplex(a, f)", mustListComplex(t, s, "g", "k", "n", "a", "f"), "a", "b", "c", "d", "e")
+	wantKeys(t, "ListComplex(c, c)", mustListComplex(t, s, "g", "k", "n", "c", "c"))
+	wantKeys(t, "ListComplex(f, z)", mustListComplex(t, s, "g", "k", "n", "f", "z"))
+}
+
+func testListComplexScopedByPrefix(t *testing.T, s api.Store) {
+	mustPut(t, s, ck("g", "k", "n", "a"), "1")
+	mustPut(t, s, ck("g", "k", "m", "b"), "2")
+	mustPut(t, s, ck("g", "j", "n", "c"), "3")
+	mustPut(t, s, ck("h", "k", "n", "d"), "4")
+	mustPutState(t, s, "e", "5")
+	wantKeys(t, "ListComplex(g, k, n)", mustListComplex(t, s, "g", "k", "n", "", "\xff"), "a")
+}
+
+func testScanOrdered(t *testing.T, s api.Store) {
+	for _, u := range []string{"c", "a", "b"} {
+		mustPut(t, s, ck("g", "k", "n", u), "v"+u)
+	}
+	mustPut(t, s, ck("g", "k", "other", "z"), "vz")
+	it := mustScan(t, s, "g", "k", "n")
+	defer it.Close()
+	for _, u := range []string{"a", "b", "c"} {
+		hasNext, err := it.HasNext()
+		if err != nil {
+			t.Fatalf("HasNext error: %v", err)
+		}
+		if !hasNext {
+			t.Fatalf("HasNext = false before %q", u)
+		}
+		key, value, ok, err := it.Next()
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if !ok || string(key) != u || string(value) != "v"+u {
+			t.Fatalf("Next = %q, %q, %t; want %q, %q, true", key, value, ok, u, "v"+u)
+		}
+	}
+	wantExhausted(t, it)
+}
+
+func testScanEmpty(t *testing.T, s api.Store) {
+	it := mustScan(t, s, "g", "k", "n")
+	defer it.Close()
+	wantExhausted(t, it)
+}
+
+func testScanExhaustion(t *testing.T, s api.Store) {
+	mustPut(t, s, ck("g", "k", "n", "a"), "1")
+	it := mustScan(t, s, "g", "k", "n")
+	defer it.Close()
+	if _, _, ok, err := it.Next(); err != nil || !ok {
+		t.Fatalf("Next = %t, %v; want an entry", ok, err)
+	}
+	for i := 0; i < 3; i++ {
+		wantExhausted(t, it)
+	}
+}
+
+func testScanCloseTwice(t *testing.T, s api.Store) {
+	mustPut(t, s, ck("g", "k", "n", "a"), "1")
+	it := mustScan(t, s, "g", "k", "n")
+	if err := it.Close(); err != nil {
+		t.Fatalf("first iterator Close error: %v", err)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatalf("second iterator Close error: %v", err)
+	}
+}
+
+func testScanAfterClose(t *testing.T, s api.Store) {
+	mustPut(t, s, ck("g", "k", "n", "a"), "1")
+	it := mustScan(t, s, "g", "k", "n")
+	if err := it.Close(); err != nil {
+		t.Fatalf("iterator Close error: %v", err)
+	}
+	if _, err := it.HasNext(); !isClosedError(err) {
+		t.Fatalf("HasNext after Close error = %v, want %s", err, api.ErrRuntimeClosed)
+	}
+	if _, _, _, err := it.Next(); !isClosedError(err) {
+		t.Fatalf("Next after Close error = %v, want %s", err, api.ErrRuntimeClosed)
+	}
+}
+
+func testCloseTwice(t *testing.T, s api.Store) {
+	if err := s.Close(); err != nil {
+		t.Fatalf("first Close error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close error: %v", err)
+	}
+}
+
+func testCloseOperationsFail(t *testing.T, s api.Store) {
+	key := ck("g", "k", "n", "u")
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	ops := []struct {
+		name string
+		call func() error
+	}{
+		{"PutState", func() error { return s.PutState([]byte("k"), []byte("v")) }},
+		{"GetState", func() error { _, _, err := s.GetState([]byte("k")); return err }},
+		{"DeleteState", func() error { return s.DeleteState([]byte("k")) }},
+		{"ListStates", func() error { _, err := s.ListStates([]byte("a"), []byte("z")); return err }},
+		{"Put", func() error { return s.Put(key, []byte("v")) }},
+		{"Get", func() error { _, _, err := s.Get(key); return err }},
+		{"Delete", func() error { return s.Delete(key) }},
+		{"Merge", func() error { return s.Merge(key, []byte("v")) }},
+		{"DeletePrefix", func() error { return s.DeletePrefix(key) }},
+		{"ListComplex", func() error {
+			_, err := s.ListComplex(key.KeyGroup, key.Key, key.Namespace, []byte("a"), []byte("z"))
+			return err
+		}},
+		{"ScanComplex", func() error { _, err := s.ScanComplex(key.KeyGroup, key.Key, key.Namespace); return err }},
+	}
+	for _, op := range ops {
+		if err := op.call(); !isClosedError(err) {
+			t.Errorf("%s after Close error = %v, want %s", op.name, err, api.ErrRuntimeClosed)
+		}
+	}
+}
+
+func isClosedError(err error) bool {
+	var sdkErr *api.SDKError
+	return errors.As(err, &sdkErr) && sdkErr.Code == api.ErrRuntimeClosed
+}
+
+func ck(keyGroup, key, namespace, userKey string) api.ComplexKey {
+	return api.ComplexKey{
+		KeyGroup:  []byte(keyGroup),
+		Key:       []byte(key),
+		Namespace: []byte(namespace),
+		UserKey:   []byte(userKey),
+	}
+}
+
+func describe(key api.ComplexKey) string {
+	return "(" + string(key.KeyGroup) + ", " + string(key.Key) + ", " + string(key.Namespace) + ", " + string(key.UserKey) + ")"
+}
+
+func mustPutState(t *testing.T, s api.Store, key, value string) {
+	t.Helper()
+	if err := s.PutState([]byte(key), []byte(value)); err != nil {
+		t.Fatalf("PutState(%q) error: %v", key, err)
+	}
+}
+
+func mustGetState(t *testing.T, s api.Store, key string) ([]byte, bool) {
+	t.Helper()
+	val, found, err := s.GetState([]byte(key))
+	if err != nil {
+		t.Fatalf("GetState(%q) error: %v", key, err)
+	}
+	return val, found
+}
+
+func wantState(t *testing.T, s api.Store, key, value string) {
+	t.Helper()
+	got, found := mustGetState(t, s, key)
+	if !found || string(got) != value {
+		t.Fatalf("GetState(%q) = %q, %t; want %q, true", key, got, found, value)
+	}
+}
+
+func wantNoState(t *testing.T, s api.Store, key string) {
+	t.Helper()
+	if got, found := mustGetState(t, s, key); found {
+		t.Fatalf("GetState(%q) = %q, true; want not found", key, got)
+	}
+}
+
+func mustListStates(t *testing.T, s api.Store, start, end string) [][]byte {
+	t.Helper()
+	keys, err := s.ListStates([]byte(start), []byte(end))
+	if err != nil {
+		t.Fatalf("ListStates(%q, %q) error: %v", start, end, err)
+	}
+	return keys
+}
+
+func mustPut(t *testing.T, s api.Store, key api.ComplexKey, value string) {
+	t.Helper()
+	if err := s.Put(key, []byte(value)); err != nil {
+		t.Fatalf("Put(%s) error: %v", describe(key), err)
+	}
+}
+
+func mustGet(t *testing.T, s api.Store, key api.ComplexKey) ([]byte, bool) {
+	t.Helper()
+	val, found, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%s) error: %v", describe(key), err)
+	}
+	return val, found
+}
+
+func want(t *testing.T, s api.Store, key api.ComplexKey, value string) {
+	t.Helper()
+	got, found := mustGet(t, s, key)
+	if !found || string(got) != value {
+		t.Fatalf("Get(%s) = %q, %t; want %q, true", describe(key), got, found, value)
+	}
+}
+
+func mustListComplex(t *testing.T, s api.Store, keyGroup, key, namespace, start, end string) [][]byte {
+	t.Helper()
+	keys, err := s.ListComplex([]byte(keyGroup), []byte(key), []byte(namespace), []byte(start), []byte(end))
+	if err != nil {
+		t.Fatalf("ListComplex(%q, %q, %q, %q, %q) error: %v", keyGroup, key, namespace, start, end, err)
+	}
+	return keys
+}
+
+func mustScan(t *testing.T, s api.Store, keyGroup, key, namespace string) api.Iterator {
+	t.Helper()
+	it, err := s.ScanComplex([]byte(keyGroup), []byte(key), []byte(namespace))
+	if err != nil {
+		t.Fatalf("ScanComplex(%q, %q, %q) error: %v", keyGroup, key, namespace, err)
+	}
+	if it == nil {
+		t.Fatal("ScanComplex returned a nil iterator")
+	}
+	return it
+}
+
+func wantExhausted(t *testing.T, it api.Iterator) {
+	t.Helper()
+	hasNext, err := it.HasNext()
+	if err != nil {
+		t.Fatalf("HasNext error: %v", err)
+	}
+	if hasNext {
+		t.Fatal("HasNext = true on an exhausted iterator")
+	}
+	key, value, ok, err := it.Next()
+	if err != nil {
+		t.Fatalf("Next on an exhausted iterator error: %v", err)
+	}
+	if ok || key != nil || value != nil {
+		t.Fatalf("Next on an exhausted iterator = %q, %q, %t; want nil, nil, false", key, value, ok)
+	}
+}
+
+func wantKeys(t *testing.T, what string, got [][]byte, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %q, want %q", what, got, want)
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], []byte(want[i])) {
+			t.Fatalf("%s = %q, want %q", what, got, want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trace records every export a driver receives and every host call it
+// makes (collector emits, kv store and iterator methods) to a compact binary
+// trace, and replays a trace against a driver natively, reporting the first
+// point where the driver's calls diverge from the recording.
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Kind identifies an export or host call; names follow processor.wit.
+type Kind uint8
+
+const (
+	KindInit Kind = iota + 1
+	KindProcess
+	KindProcessWatermark
+	KindTakeCheckpoint
+	KindCheckHeartbeat
+	KindClose
+	KindExec
+	KindCustom
+	// KindExportEnd carries the results of the export begun before it.
+	KindExportEnd
+	// KindPanic records a driver panic in place of KindExportEnd.
+	KindPanic
+
+	KindEmit
+	KindEmitWatermark
+	KindOpenStore
+	KindContextClose
+	KindPutState
+	KindGetState
+	KindDeleteState
+	KindListStates
+	KindPut
+	KindGet
+	KindDelete
+	KindMerge
+	KindDeletePrefix
+	KindListComplex
+	KindScanComplex
+	KindStoreClose
+	KindIteratorHasNext
+	KindIteratorNext
+	KindIteratorClose
+
+	// KindProcessBatch is the fs-process-batch export.
+	KindProcessBatch
+	// KindContextFlush records a driver's explicit api.Flush call.
+	KindContextFlush
+
+	kindLimit
+)
+
+var kindNames = map[Kind]string{
+	KindInit:             "fs-init",
+	KindProcess:          "fs-process",
+	KindProcessBatch:     "fs-process-batch",
+	KindProcessWatermark: "fs-process-watermark",
+	KindTakeCheckpoint:   "fs-take-checkpoint",
+	KindCheckHeartbeat:   "fs-check-heartbeat",
+	KindClose:            "fs-close",
+	KindExec:             "fs-exec",
+	KindCustom:           "fs-custom",
+	KindExportEnd:        "export-end",
+	KindPanic:            "panic",
+	KindEmit:             "collector.emit",
+	KindEmitWatermark:    "collector.emit-watermark",
+	KindOpenStore:        "kv.store",
+	KindContextClose:     "context.close",
+	KindContextFlush:     "context.flush",
+	KindPutState:         "kv.store.put-state",
+	KindGetState:         "kv.store.get-state",
+	KindDeleteState:      "kv.store.delete-state",
+	KindListStates:       "kv.store.list-states",
+	KindPut:              "kv.store.put",
+	KindGet:              "kv.store.get",
+	KindDelete:           "kv.store.delete",
+	KindMerge:            "kv.store.merge",
+	KindDeletePrefix:     "kv.store.delete-prefix",
+	KindListComplex:      "kv.store.list-complex",
+	KindScanComplex:      "kv.store.scan-complex",
+	KindStoreClose:       "kv.store.drop",
+	KindIteratorHasNext:  "kv.iterator.has-next",
+	KindIteratorNext:     "kv.iterator.next",
+	KindIteratorClose:    "kv.iterator.drop",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// IsExport reports whether k starts an export invocation.
+func (k Kind) IsExport() bool {
+	return k >= KindInit && k <= KindCustom || k == KindProcessBatch
+}
+
+// ErrorInfo is a recorded error result.
+type ErrorInfo struct {
+	Code    api.ErrorCode
+	Message string
+}
+
+// Error rebuilds the error a call returned: an SDKError when it had a code.
+func (e *ErrorInfo) Error() error {
+	if e == nil {
+		return nil
+	}
+	if e.Code != "" {
+		return &api.SDKError{Code: e.Code, Message: e.Message}
+	}
+	return errors.New(e.Message)
+}
+
+func errorInfo(err error) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+	var sdkErr *api.SDKError
+	if errors.As(err, &sdkErr) {
+		return &ErrorInfo{Code: sdkErr.Code, Message: sdkErr.Message}
+	}
+	return &ErrorInfo{Message: err.Error()}
+}
+
+// Event is one record in a trace. Handle names the store or iterator a kv
+// call was made on; handles are numbered from 1 in creation order. Numeric
+// arguments and results are uvarint-encoded.
+type Event struct {
+	Kind    Kind
+	Handle  uint32
+	Args    [][]byte
+	Results [][]byte
+	Err     *ErrorInfo
+}
+
+// numericArgs and numericResults count the leading uvarint fields per kind,
+// so String can print them as numbers.
+var (
+	numericArgs = map[Kind]int{
+		KindProcess:          1,
+		KindProcessBatch:     1,
+		KindProcessWatermark: 2,
+		KindTakeCheckpoint:   1,
+		KindEmit:             1,
+		KindEmitWatermark:    2,
+	}
+	numericResults = map[Kind]int{
+		KindOpenStore:   1,
+		KindScanComplex: 1,
+	}
+)
+
+func (e Event) String() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.String())
+	if e.Handle != 0 {
+		fmt.Fprintf(&b, "#%d", e.Handle)
+	}
+	b.WriteByte('(')
+	for i, arg := range e.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeField(&b, arg, i < numericArgs[e.Kind])
+	}
+	b.WriteByte(')')
+	if len(e.Results) > 0 {
+		b.WriteString(" ->")
+		for i, result := range e.Results {
+			b.WriteByte(' ')
+			writeField(&b, result, i < numericResults[e.Kind])
+		}
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, " error %q", e.Err.Error().Error())
+	}
+	return b.String()
+}
+
+func writeField(b *strings.Builder, field []byte, numeric bool) {
+	if numeric {
+		b.WriteString(strconv.FormatUint(bytesUint(field), 10))
+		return
+	}
+	b.WriteString(strconv.Quote(string(field)))
+}
+
+func uintBytes(v uint64) []byte {
+	return binary.AppendUvarint(nil, v)
+}
+
+func bytesUint(b []byte) uint64 {
+	v, _ := binary.Uvarint(b)
+	return v
+}
+
+func boolBytes(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func bytesBool(b []byte) bool {
+	return len(b) == 1 && b[0] == 1
+}
+
+var magic = []byte("FSTRACE1")
+
+// Limits guard against allocating huge buffers for a corrupt trace.
+const (
+	maxListLen  = 1 << 20
+	maxBytesLen = 1 << 30
+)
+
+// Writer appends events to a trace. Each event is written with a single
+// Write call, so a trace cut short by a crash ends on an event boundary as
+// long as the underlying writer does not split writes.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter writes the trace header to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	if _, err := w.Write(magic); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// Write appends one event.
+func (w *Writer) Write(e Event) error {
+	buf := w.buf[:0]
+	buf = append(buf, byte(e.Kind))
+	buf = binary.AppendUvarint(buf, uint64(e.Handle))
+	buf = appendList(buf, e.Args)
+	buf = appendList(buf, e.Results)
+	if e.Err == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		buf = appendBytes(buf, []byte(e.Err.Code))
+		buf = appendBytes(buf, []byte(e.Err.Message))
+	}
+	w.buf = buf
+	_, err := w.w.Write(buf)
+	return err
+}
+
+func appendList(buf []byte, list [][]byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(list)))
+	for _, item := range list {
+		buf = appendBytes(buf, item)
+	}
+	return buf
+}
+
+func appendBytes(buf []byte, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// Reader reads events from a trace.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the trace header.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("read trace header: %w", err)
+	}
+	if string(header) != string(magic) {
+		return nil, fmt.Errorf("not a trace file (header %q)", header)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next event, io.EOF at the end of the trace, or
+// io.ErrUnexpectedEOF when the trace stops inside an event.
+func (r *Reader) Next() (Event, error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return Event{}, err
+	}
+	if kind == 0 || Kind(kind) >= kindLimit {
+		return Event{}, fmt.Errorf("invalid event kind %d", kind)
+	}
+	e := Event{Kind: Kind(kind)}
+	handle, err := r.uvarint()
+	if err != nil {
+		return Event{}, err
+	}
+	e.Handle = uint32(handle)
+	if e.Args, err = r.list(); err != nil {
+		return Event{}, err
+	}
+	if e.Results, err = r.list(); err != nil {
+		return Event{}, err
+	}
+	hasErr, err := r.r.ReadByte()
+	if err != nil {
+		return Event{}, unexpected(err)
+	}
+	if hasErr == 1 {
+		code, err := r.bytes()
+		if err != nil {
+			return Event{}, err
+		}
+		message, err := r.bytes()
+		if err != nil {
+			return Event{}, err
+		}
+		e.Err = &ErrorInfo{Code: api.ErrorCode(code), Message: string(message)}
+	}
+	return e, nil
+}
+
+// ReadAll reads every event; a trailing partial event is dropped.
+func ReadAll(r io.Reader) ([]Event, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var out []Event
+	for {
+		e, err := reader.Next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, e)
+	}
+}
+
+func (r *Reader) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	return v, nil
+}
+
+func (r *Reader) list() ([][]byte, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxListLen {
+		return nil, fmt.Errorf("list of %d items exceeds limit", n)
+	}
+	out := make([][]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		item, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func (r *Reader) bytes() ([]byte, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBytesLen {
+		return nil, fmt.Errorf("field of %d bytes exceeds limit", n)
+	}
+	out := make([]byte, n)
+	if _, err := io.ReadFull(r.r, out); err != nil {
+		return nil, unexpected(err)
+	}
+	return out, nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
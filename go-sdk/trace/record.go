@@ -0,0 +1,408 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Recorder is an api.Driver that forwards to the wrapped driver and writes
+// every export and every host call made through its context to a trace:
+//
+//	recorder, err := trace.NewRecorder(&MyProcessor{}, traceWriter)
+//	...
+//	fssdk.Run(recorder.Driver())
+//
+// Recording never changes what the driver sees; a failing trace writer only
+// stops the trace, and Err reports why.
+type Recorder struct {
+	driver api.Driver
+
+	mu      sync.Mutex
+	w       *Writer
+	err     error
+	handles uint32
+	inner   api.Context
+	ctx     *recordingContext
+}
+
+var (
+	_ api.Driver         = (*Recorder)(nil)
+	_ api.BatchProcessor = (*batchRecorder)(nil)
+)
+
+// NewRecorder wraps driver and writes the trace header to w.
+func NewRecorder(driver api.Driver, w io.Writer) (*Recorder, error) {
+	if driver == nil {
+		return nil, api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil")
+	}
+	tw, err := NewWriter(w)
+	if err != nil {
+		return nil, fmt.Errorf("write trace header: %w", err)
+	}
+	return &Recorder{driver: driver, w: tw}, nil
+}
+
+// Driver returns the recorder as the driver to run. It is an
+// api.BatchProcessor only if the wrapped driver is one, so batch handling is
+// unchanged.
+func (r *Recorder) Driver() api.Driver {
+	if _, ok := r.driver.(api.BatchProcessor); ok {
+		return &batchRecorder{Recorder: r}
+	}
+	return r
+}
+
+// Err returns the first error from the trace writer.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) Init(ctx api.Context, config map[string]string) error {
+	_, err := r.export(KindInit, ctx, configArgs(config), func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.Init(c, config)
+	})
+	return err
+}
+
+func (r *Recorder) Process(ctx api.Context, sourceID uint32, data []byte) error {
+	_, err := r.export(KindProcess, ctx, [][]byte{uintBytes(uint64(sourceID)), data}, func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.Process(c, sourceID, data)
+	})
+	return err
+}
+
+func (r *Recorder) ProcessWatermark(ctx api.Context, sourceID uint32, watermark uint64) error {
+	args := [][]byte{uintBytes(uint64(sourceID)), uintBytes(watermark)}
+	_, err := r.export(KindProcessWatermark, ctx, args, func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.ProcessWatermark(c, sourceID, watermark)
+	})
+	return err
+}
+
+func (r *Recorder) TakeCheckpoint(ctx api.Context, checkpointID uint64) error {
+	_, err := r.export(KindTakeCheckpoint, ctx, [][]byte{uintBytes(checkpointID)}, func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.TakeCheckpoint(c, checkpointID)
+	})
+	return err
+}
+
+func (r *Recorder) CheckHeartbeat(ctx api.Context) bool {
+	var alive bool
+	_, _ = r.export(KindCheckHeartbeat, ctx, nil, func(c api.Context) ([][]byte, error) {
+		alive = r.driver.CheckHeartbeat(c)
+		return [][]byte{boolBytes(alive)}, nil
+	})
+	return alive
+}
+
+func (r *Recorder) Close(ctx api.Context) error {
+	_, err := r.export(KindClose, ctx, nil, func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.Close(c)
+	})
+	return err
+}
+
+func (r *Recorder) Exec(ctx api.Context, className string, modules []api.Module) error {
+	_, err := r.export(KindExec, ctx, execArgs(className, modules), func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.Exec(c, className, modules)
+	})
+	return err
+}
+
+func (r *Recorder) Custom(ctx api.Context, payload []byte) ([]byte, error) {
+	var response []byte
+	_, err := r.export(KindCustom, ctx, [][]byte{payload}, func(c api.Context) ([][]byte, error) {
+		var err error
+		response, err = r.driver.Custom(c, payload)
+		return [][]byte{response}, err
+	})
+	return response, err
+}
+
+// batchRecorder is the Recorder of an api.BatchProcessor.
+type batchRecorder struct {
+	*Recorder
+}
+
+// ProcessBatch records one fs-process-batch export.
+func (r *batchRecorder) ProcessBatch(ctx api.Context, sourceID uint32, records [][]byte) error {
+	args := append([][]byte{uintBytes(uint64(sourceID))}, records...)
+	_, err := r.export(KindProcessBatch, ctx, args, func(c api.Context) ([][]byte, error) {
+		return nil, r.driver.(api.BatchProcessor).ProcessBatch(c, sourceID, records)
+	})
+	return err
+}
+
+// export records the begin event, runs call with a recording context and
+// records its results. A panic is recorded and then re-raised.
+func (r *Recorder) export(kind Kind, ctx api.Context, args [][]byte, call func(api.Context) ([][]byte, error)) (results [][]byte, err error) {
+	c := r.context(ctx)
+	r.write(Event{Kind: kind, Args: args})
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.write(Event{Kind: KindPanic, Results: [][]byte{[]byte(fmt.Sprint(recovered))}})
+			panic(recovered)
+		}
+		r.write(Event{Kind: KindExportEnd, Results: results, Err: errorInfo(err)})
+	}()
+	return call(c)
+}
+
+// context reuses the recording wrapper while the runtime keeps the same context.
+func (r *Recorder) context(ctx api.Context) *recordingContext {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx == nil || r.inner != ctx {
+		r.inner = ctx
+		r.ctx = &recordingContext{rec: r, inner: ctx}
+	}
+	return r.ctx
+}
+
+func (r *Recorder) nextHandle() uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handles++
+	return r.handles
+}
+
+func (r *Recorder) write(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.w.Write(e)
+}
+
+func configArgs(config map[string]string) [][]byte {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([][]byte, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, []byte(k), []byte(config[k]))
+	}
+	return args
+}
+
+func argsConfig(args [][]byte) map[string]string {
+	config := make(map[string]string, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		config[string(args[i])] = string(args[i+1])
+	}
+	return config
+}
+
+func execArgs(className string, modules []api.Module) [][]byte {
+	args := make([][]byte, 0, 1+2*len(modules))
+	args = append(args, []byte(className))
+	for _, m := range modules {
+		args = append(args, []byte(m.Name), m.Bytes)
+	}
+	return args
+}
+
+func argsExec(args [][]byte) (string, []api.Module) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	modules := make([]api.Module, 0, (len(args)-1)/2)
+	for i := 1; i+1 < len(args); i += 2 {
+		modules = append(modules, api.Module{Name: string(args[i]), Bytes: args[i+1]})
+	}
+	return string(args[0]), modules
+}
+
+func complexKeyArgs(key api.ComplexKey) [][]byte {
+	return [][]byte{key.KeyGroup, key.Key, key.Namespace, key.UserKey}
+}
+
+type recordingContext struct {
+	rec   *Recorder
+	inner api.Context
+}
+
+func (c *recordingContext) Emit(targetID uint32, data []byte) error {
+	err := c.inner.Emit(targetID, data)
+	c.rec.write(Event{Kind: KindEmit, Args: [][]byte{uintBytes(uint64(targetID)), data}, Err: errorInfo(err)})
+	return err
+}
+
+func (c *recordingContext) EmitWatermark(targetID uint32, watermark uint64) error {
+	err := c.inner.EmitWatermark(targetID, watermark)
+	args := [][]byte{uintBytes(uint64(targetID)), uintBytes(watermark)}
+	c.rec.write(Event{Kind: KindEmitWatermark, Args: args, Err: errorInfo(err)})
+	return err
+}
+
+func (c *recordingContext) Flush() error {
+	err := api.Flush(c.inner)
+	c.rec.write(Event{Kind: KindContextFlush, Err: errorInfo(err)})
+	return err
+}
+
+func (c *recordingContext) GetOrCreateStore(name string) (api.Store, error) {
+	store, err := c.inner.GetOrCreateStore(name)
+	e := Event{Kind: KindOpenStore, Args: [][]byte{[]byte(name)}, Err: errorInfo(err)}
+	if err != nil {
+		c.rec.write(e)
+		return nil, err
+	}
+	handle := c.rec.nextHandle()
+	e.Results = [][]byte{uintBytes(uint64(handle))}
+	c.rec.write(e)
+	return &recordingStore{rec: c.rec, handle: handle, inner: store}, nil
+}
+
+func (c *recordingContext) Config() map[string]string {
+	return c.inner.Config()
+}
+
+func (c *recordingContext) Close() error {
+	err := c.inner.Close()
+	c.rec.write(Event{Kind: KindContextClose, Err: errorInfo(err)})
+	return err
+}
+
+type recordingStore struct {
+	rec    *Recorder
+	handle uint32
+	inner  api.Store
+}
+
+func (s *recordingStore) record(kind Kind, args [][]byte, results [][]byte, err error) {
+	s.rec.write(Event{Kind: kind, Handle: s.handle, Args: args, Results: results, Err: errorInfo(err)})
+}
+
+func (s *recordingStore) PutState(key []byte, value []byte) error {
+	err := s.inner.PutState(key, value)
+	s.record(KindPutState, [][]byte{key, value}, nil, err)
+	return err
+}
+
+func (s *recordingStore) GetState(key []byte) ([]byte, bool, error) {
+	value, found, err := s.inner.GetState(key)
+	s.record(KindGetState, [][]byte{key}, [][]byte{boolBytes(found), value}, err)
+	return value, found, err
+}
+
+func (s *recordingStore) DeleteState(key []byte) error {
+	err := s.inner.DeleteState(key)
+	s.record(KindDeleteState, [][]byte{key}, nil, err)
+	return err
+}
+
+func (s *recordingStore) ListStates(startInclusive []byte, endExclusive []byte) ([][]byte, error) {
+	keys, err := s.inner.ListStates(startInclusive, endExclusive)
+	s.record(KindListStates, [][]byte{startInclusive, endExclusive}, keys, err)
+	return keys, err
+}
+
+func (s *recordingStore) Put(key api.ComplexKey, value []byte) error {
+	err := s.inner.Put(key, value)
+	s.record(KindPut, append(complexKeyArgs(key), value), nil, err)
+	return err
+}
+
+func (s *recordingStore) Get(key api.ComplexKey) ([]byte, bool, error) {
+	value, found, err := s.inner.Get(key)
+	s.record(KindGet, complexKeyArgs(key), [][]byte{boolBytes(found), value}, err)
+	return value, found, err
+}
+
+func (s *recordingStore) Delete(key api.ComplexKey) error {
+	err := s.inner.Delete(key)
+	s.record(KindDelete, complexKeyArgs(key), nil, err)
+	return err
+}
+
+func (s *recordingStore) Merge(key api.ComplexKey, value []byte) error {
+	err := s.inner.Merge(key, value)
+	s.record(KindMerge, append(complexKeyArgs(key), value), nil, err)
+	return err
+}
+
+func (s *recordingStore) DeletePrefix(key api.ComplexKey) error {
+	err := s.inner.DeletePrefix(key)
+	s.record(KindDeletePrefix, complexKeyArgs(key), nil, err)
+	return err
+}
+
+func (s *recordingStore) ListComplex(
+	keyGroup []byte,
+	key []byte,
+	namespace []byte,
+	startInclusive []byte,
+	endExclusive []byte,
+) ([][]byte, error) {
+	keys, err := s.inner.ListComplex(keyGroup, key, namespace, startInclusive, endExclusive)
+	s.record(KindListComplex, [][]byte{keyGroup, key, namespace, startInclusive, endExclusive}, keys, err)
+	return keys, err
+}
+
+func (s *recordingStore) ScanComplex(keyGroup []byte, key []byte, namespace []byte) (api.Iterator, error) {
+	it, err := s.inner.ScanComplex(keyGroup, key, namespace)
+	args := [][]byte{keyGroup, key, namespace}
+	if err != nil {
+		s.record(KindScanComplex, args, nil, err)
+		return nil, err
+	}
+	handle := s.rec.nextHandle()
+	s.record(KindScanComplex, args, [][]byte{uintBytes(uint64(handle))}, nil)
+	return &recordingIterator{rec: s.rec, handle: handle, inner: it}, nil
+}
+
+func (s *recordingStore) Close() error {
+	err := s.inner.Close()
+	s.record(KindStoreClose, nil, nil, err)
+	return err
+}
+
+type recordingIterator struct {
+	rec    *Recorder
+	handle uint32
+	inner  api.Iterator
+}
+
+func (i *recordingIterator) record(kind Kind, results [][]byte, err error) {
+	i.rec.write(Event{Kind: kind, Handle: i.handle, Results: results, Err: errorInfo(err)})
+}
+
+func (i *recordingIterator) HasNext() (bool, error) {
+	hasNext, err := i.inner.HasNext()
+	i.record(KindIteratorHasNext, [][]byte{boolBytes(hasNext)}, err)
+	return hasNext, err
+}
+
+func (i *recordingIterator) Next() ([]byte, []byte, bool, error) {
+	key, value, ok, err := i.inner.Next()
+	i.record(KindIteratorNext, [][]byte{boolBytes(ok), key, value}, err)
+	return key, value, ok, err
+}
+
+func (i *recordingIterator) Close() error {
+	err := i.inner.Close()
+	i.record(KindIteratorClose, nil, err)
+	return err
+}
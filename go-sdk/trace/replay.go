@@ -0,0 +1,376 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+
+	"github.com/functionstream/function-stream/go-sdk/api"
+)
+
+// Divergence reports the first trace event the driver did not reproduce.
+// Want is the recorded event (nil past the end of the trace) and Got is what
+// the driver did instead (nil when it skipped Want).
+type Divergence struct {
+	Index int
+	Want  *Event
+	Got   *Event
+}
+
+func (d *Divergence) Error() string {
+	switch {
+	case d.Want == nil:
+		return fmt.Sprintf("trace event %d: driver made %s after the end of the trace", d.Index, d.Got)
+	case d.Got == nil:
+		return fmt.Sprintf("trace event %d: driver did not make %s", d.Index, d.Want)
+	default:
+		return fmt.Sprintf("trace event %d: driver made %s, trace has %s", d.Index, d.Got, d.Want)
+	}
+}
+
+// PanicError reports a driver panic during replay. Recorded tells whether the
+// trace recorded a panic at the same point.
+type PanicError struct {
+	Index    int
+	Export   Kind
+	Value    any
+	Stack    []byte
+	Recorded bool
+}
+
+func (e *PanicError) Error() string {
+	what := "unexpected panic"
+	if e.Recorded {
+		what = "reproduced panic"
+	}
+	return fmt.Sprintf("trace event %d (%s): %s: %v\n%s", e.Index, e.Export, what, e.Value, e.Stack)
+}
+
+// ReplayFile replays the trace at path against driver.
+func ReplayFile(driver api.Driver, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open trace: %w", err)
+	}
+	defer f.Close()
+	return Replay(driver, f)
+}
+
+// Replay feeds every recorded export to driver and answers its host calls with
+// the recorded results, so no store or host is needed. It returns nil when the
+// driver makes exactly the recorded calls with the recorded arguments and
+// returns the recorded results, a *Divergence at the first difference, or a
+// *PanicError when the driver panics.
+func Replay(driver api.Driver, r io.Reader) error {
+	if driver == nil {
+		return api.NewError(api.ErrRuntimeInvalidDriver, "driver must not be nil")
+	}
+	events, err := ReadAll(r)
+	if err != nil {
+		return err
+	}
+	p := &replayer{driver: driver, events: events}
+	p.ctx = &replayContext{p: p, config: map[string]string{}}
+	for p.pos < len(p.events) {
+		if err := p.export(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type replayer struct {
+	driver     api.Driver
+	events     []Event
+	pos        int
+	ctx        *replayContext
+	divergence *Divergence
+}
+
+func (p *replayer) export() error {
+	begin := p.events[p.pos]
+	if !begin.Kind.IsExport() {
+		return fmt.Errorf("trace event %d: %s outside an export", p.pos, begin)
+	}
+	p.pos++
+	results, panicked, callErr := p.invoke(begin)
+	if p.divergence != nil {
+		return p.divergence
+	}
+	if panicked != nil {
+		panicked.Index = p.pos
+		panicked.Export = begin.Kind
+		panicked.Recorded = p.pos < len(p.events) && p.events[p.pos].Kind == KindPanic
+		return panicked
+	}
+	got := Event{Kind: KindExportEnd, Results: results, Err: errorInfo(callErr)}
+	if p.pos >= len(p.events) {
+		return &Divergence{Index: p.pos, Got: &got}
+	}
+	want := p.events[p.pos]
+	if want.Kind != KindExportEnd || !equalLists(want.Results, got.Results) || !equalErrors(want.Err, got.Err) {
+		if want.Kind != KindExportEnd && want.Kind != KindPanic {
+			// The driver returned before making a recorded host call.
+			return &Divergence{Index: p.pos, Want: &want}
+		}
+		return &Divergence{Index: p.pos, Want: &want, Got: &got}
+	}
+	p.pos++
+	return nil
+}
+
+// invoke runs one export. Divergences inside host calls unwind the driver
+// with a panic carrying the *Divergence; other panics are returned.
+func (p *replayer) invoke(begin Event) (results [][]byte, panicked *PanicError, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if d, ok := recovered.(*Divergence); ok && d == p.divergence {
+				return
+			}
+			panicked = &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	ctx := p.ctx
+	args := begin.Args
+	switch begin.Kind {
+	case KindInit:
+		config := argsConfig(args)
+		ctx.config = config
+		return nil, nil, p.driver.Init(ctx, cloneConfig(config))
+	case KindProcess:
+		return nil, nil, p.driver.Process(ctx, uint32(bytesUint(arg(args, 0))), arg(args, 1))
+	case KindProcessBatch:
+		var records [][]byte
+		if len(args) > 1 {
+			records = args[1:]
+		}
+		return nil, nil, api.ProcessBatch(p.driver, ctx, uint32(bytesUint(arg(args, 0))), records)
+	case KindProcessWatermark:
+		return nil, nil, p.driver.ProcessWatermark(ctx, uint32(bytesUint(arg(args, 0))), bytesUint(arg(args, 1)))
+	case KindTakeCheckpoint:
+		return nil, nil, p.driver.TakeCheckpoint(ctx, bytesUint(arg(args, 0)))
+	case KindCheckHeartbeat:
+		return [][]byte{boolBytes(p.driver.CheckHeartbeat(ctx))}, nil, nil
+	case KindClose:
+		return nil, nil, p.driver.Close(ctx)
+	case KindExec:
+		className, modules := argsExec(args)
+		return nil, nil, p.driver.Exec(ctx, className, modules)
+	case KindCustom:
+		response, err := p.driver.Custom(ctx, arg(args, 0))
+		return [][]byte{response}, nil, err
+	}
+	return nil, nil, fmt.Errorf("unknown export %s", begin.Kind)
+}
+
+// call matches a host call against the next recorded event and returns it.
+func (p *replayer) call(kind Kind, handle uint32, args ...[]byte) Event {
+	got := Event{Kind: kind, Handle: handle, Args: args}
+	if p.pos >= len(p.events) {
+		p.diverge(&Divergence{Index: p.pos, Got: &got})
+	}
+	want := p.events[p.pos]
+	if want.Kind != kind || want.Handle != handle || !equalLists(want.Args, args) {
+		p.diverge(&Divergence{Index: p.pos, Want: &want, Got: &got})
+	}
+	p.pos++
+	return want
+}
+
+func (p *replayer) diverge(d *Divergence) {
+	p.divergence = d
+	panic(d)
+}
+
+func arg(args [][]byte, idx int) []byte {
+	if idx < len(args) {
+		return args[idx]
+	}
+	return nil
+}
+
+func equalLists(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalErrors(a, b *ErrorInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func cloneConfig(config map[string]string) map[string]string {
+	out := make(map[string]string, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	return out
+}
+
+type replayContext struct {
+	p      *replayer
+	config map[string]string
+}
+
+func (c *replayContext) Emit(targetID uint32, data []byte) error {
+	return c.p.call(KindEmit, 0, uintBytes(uint64(targetID)), data).Err.Error()
+}
+
+func (c *replayContext) EmitWatermark(targetID uint32, watermark uint64) error {
+	return c.p.call(KindEmitWatermark, 0, uintBytes(uint64(targetID)), uintBytes(watermark)).Err.Error()
+}
+
+func (c *replayContext) Flush() error {
+	return c.p.call(KindContextFlush, 0).Err.Error()
+}
+
+func (c *replayContext) GetOrCreateStore(name string) (api.Store, error) {
+	e := c.p.call(KindOpenStore, 0, []byte(name))
+	if e.Err != nil {
+		return nil, e.Err.Error()
+	}
+	return &replayStore{p: c.p, handle: uint32(bytesUint(arg(e.Results, 0)))}, nil
+}
+
+func (c *replayContext) Config() map[string]string {
+	return cloneConfig(c.config)
+}
+
+func (c *replayContext) Close() error {
+	return c.p.call(KindContextClose, 0).Err.Error()
+}
+
+type replayStore struct {
+	p      *replayer
+	handle uint32
+}
+
+func (s *replayStore) call(kind Kind, args ...[]byte) Event {
+	return s.p.call(kind, s.handle, args...)
+}
+
+func (s *replayStore) PutState(key []byte, value []byte) error {
+	return s.call(KindPutState, key, value).Err.Error()
+}
+
+func (s *replayStore) GetState(key []byte) ([]byte, bool, error) {
+	return valueResult(s.call(KindGetState, key))
+}
+
+func (s *replayStore) DeleteState(key []byte) error {
+	return s.call(KindDeleteState, key).Err.Error()
+}
+
+func (s *replayStore) ListStates(startInclusive []byte, endExclusive []byte) ([][]byte, error) {
+	return listResult(s.call(KindListStates, startInclusive, endExclusive))
+}
+
+func (s *replayStore) Put(key api.ComplexKey, value []byte) error {
+	return s.call(KindPut, append(complexKeyArgs(key), value)...).Err.Error()
+}
+
+func (s *replayStore) Get(key api.ComplexKey) ([]byte, bool, error) {
+	return valueResult(s.call(KindGet, complexKeyArgs(key)...))
+}
+
+func (s *replayStore) Delete(key api.ComplexKey) error {
+	return s.call(KindDelete, complexKeyArgs(key)...).Err.Error()
+}
+
+func (s *replayStore) Merge(key api.ComplexKey, value []byte) error {
+	return s.call(KindMerge, append(complexKeyArgs(key), value)...).Err.Error()
+}
+
+func (s *replayStore) DeletePrefix(key api.ComplexKey) error {
+	return s.call(KindDeletePrefix, complexKeyArgs(key)...).Err.Error()
+}
+
+func (s *replayStore) ListComplex(
+	keyGroup []byte,
+	key []byte,
+	namespace []byte,
+	startInclusive []byte,
+	endExclusive []byte,
+) ([][]byte, error) {
+	return listResult(s.call(KindListComplex, keyGroup, key, namespace, startInclusive, endExclusive))
+}
+
+func (s *replayStore) ScanComplex(keyGroup []byte, key []byte, namespace []byte) (api.Iterator, error) {
+	e := s.call(KindScanComplex, keyGroup, key, namespace)
+	if e.Err != nil {
+		return nil, e.Err.Error()
+	}
+	return &replayIterator{p: s.p, handle: uint32(bytesUint(arg(e.Results, 0)))}, nil
+}
+
+func (s *replayStore) Close() error {
+	return s.call(KindStoreClose).Err.Error()
+}
+
+type replayIterator struct {
+	p      *replayer
+	handle uint32
+}
+
+func (i *replayIterator) HasNext() (bool, error) {
+	e := i.p.call(KindIteratorHasNext, i.handle)
+	if e.Err != nil {
+		return false, e.Err.Error()
+	}
+	return bytesBool(arg(e.Results, 0)), nil
+}
+
+func (i *replayIterator) Next() ([]byte, []byte, bool, error) {
+	e := i.p.call(KindIteratorNext, i.handle)
+	if e.Err != nil {
+		return nil, nil, false, e.Err.Error()
+	}
+	if !bytesBool(arg(e.Results, 0)) {
+		return nil, nil, false, nil
+	}
+	return arg(e.Results, 1), arg(e.Results, 2), true, nil
+}
+
+func (i *replayIterator) Close() error {
+	return i.p.call(KindIteratorClose, i.handle).Err.Error()
+}
+
+func valueResult(e Event) ([]byte, bool, error) {
+	if e.Err != nil {
+		return nil, false, e.Err.Error()
+	}
+	if !bytesBool(arg(e.Results, 0)) {
+		return nil, false, nil
+	}
+	return arg(e.Results, 1), true, nil
+}
+
+func listResult(e Event) ([][]byte, error) {
+	if e.Err != nil {
+		return nil, e.Err.Error()
+	}
+	return e.Results, nil
+}
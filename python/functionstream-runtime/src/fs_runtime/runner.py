@@ -17,12 +17,33 @@ import sys
 from typing import List, Optional, Tuple
 
 from fs_api.driver import FSProcessorDriver
+from fs_api.store.error import KvError, KvIOError, KvNotFoundError
 
 logger = logging.getLogger(__name__)
 
 from .store.fs_context import WitContext, convert_config_to_dict
 import fs_api_advanced
 
+from wit_world.imports.types import ErrorInfo
+from wit_world.types import Err
+
+
+def _error_code(e: Exception) -> str:
+    """Map an exception to the SDK error code reported to the host."""
+    if isinstance(e, KvIOError):
+        return "store_io"
+    if isinstance(e, KvNotFoundError):
+        return "store_not_found"
+    if isinstance(e, KvError):
+        return "store_internal"
+    return "driver_error"
+
+
+def _export_error(code: str, e: Exception) -> Exception:
+    """Wrap e as the error-info result of a processor export. Only transient
+    store IO failures are retryable."""
+    return Err(ErrorInfo(code=code, message=str(e), retryable=code == "store_io"))
+
 
 _DRIVER: Optional[FSProcessorDriver] = None
 _CONTEXT: Optional[WitContext] = None
@@ -113,7 +134,7 @@ class WitWorld:
             try:
                 _DRIVER.init(_CONTEXT, _CONTEXT._CONFIG)
             except Exception as e:
-                logger.debug("Driver init failed (graceful degradation): %s", e)
+                raise _export_error(_error_code(e), e) from e
 
     def fs_process(self, source_id: int, data: bytes) -> None:
         if not _DRIVER or not _CONTEXT:
@@ -122,7 +143,22 @@ class WitWorld:
         try:
             _DRIVER.process(_CONTEXT, source_id, data)
         except Exception as e:
-            logger.debug("Process error (graceful degradation): %s", e)
+            raise _export_error(_error_code(e), e) from e
+
+    def fs_process_batch(self, source_id: int, records: List[bytes]) -> None:
+        if not _DRIVER or not _CONTEXT:
+            return
+
+        process_batch = getattr(_DRIVER, "process_batch", None)
+        if process_batch is None:
+            for data in records:
+                self.fs_process(source_id, data)
+            return
+
+        try:
+            process_batch(_CONTEXT, source_id, records)
+        except Exception as e:
+            raise _export_error(_error_code(e), e) from e
 
     def fs_process_watermark(self, source_id: int, watermark: int) -> None:
         if not _DRIVER or not _CONTEXT:
@@ -131,7 +167,7 @@ class WitWorld:
         try:
             _DRIVER.process_watermark(_CONTEXT, source_id, watermark)
         except Exception as e:
-            logger.debug("Watermark process error (graceful degradation): %s", e)
+            raise _export_error(_error_code(e), e) from e
 
     def fs_take_checkpoint(self, checkpoint_id: int) -> None:
         if not _DRIVER or not _CONTEXT:
@@ -140,7 +176,7 @@ class WitWorld:
         try:
             _DRIVER.take_checkpoint(_CONTEXT, checkpoint_id)
         except Exception as e:
-            logger.debug("Checkpoint error (graceful degradation): %s", e)
+            raise _export_error(_error_code(e), e) from e
 
     def fs_check_heartbeat(self) -> bool:
         if not _DRIVER or not _CONTEXT:
@@ -155,23 +191,33 @@ class WitWorld:
     def fs_close(self) -> None:
         global _DRIVER, _CONTEXT
 
-        if _DRIVER and _CONTEXT:
-            try:
-                _DRIVER.close(_CONTEXT)
-            except Exception as e:
-                logger.debug("Driver close error (cleanup continues): %s", e)
-
+        driver, context = _DRIVER, _CONTEXT
         _DRIVER = None
         _CONTEXT = None
 
+        if driver and context:
+            try:
+                driver.close(context)
+            except Exception as e:
+                raise _export_error(_error_code(e), e) from e
+
     def fs_exec(self, class_name: str, modules: List[Tuple[str, bytes]]) -> None:
-        fs_exec(class_name, modules)
+        try:
+            fs_exec(class_name, modules)
+        except Exception as e:
+            raise _export_error("driver_error", e) from e
 
     def fs_custom(self, payload: bytes) -> bytes:
         if not _DRIVER or not _CONTEXT:
-            raise RuntimeError("Driver or Context not initialized")
+            raise _export_error(
+                "runtime_not_initialized",
+                RuntimeError("Driver or Context not initialized"),
+            )
 
-        return _DRIVER.custom(payload)
+        try:
+            return _DRIVER.custom(payload)
+        except Exception as e:
+            raise _export_error(_error_code(e), e) from e
 
 
 __all__ = ['WitWorld']
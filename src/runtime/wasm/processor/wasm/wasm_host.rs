@@ -113,6 +113,8 @@ impl WasiView for HostState {
 
 impl kv::Host for HostState {}
 
+impl functionstream::core::types::Host for HostState {}
+
 impl HostStore for HostState {
     fn new(&mut self, name: String) -> Resource<FunctionStreamStoreHandle> {
         let state_store = self
@@ -271,7 +273,15 @@ impl HostStore for HostState {
         key: ComplexKey,
         value: Vec<u8>,
     ) -> Result<(), Error> {
-        self.put(self_, key, value)
+        let store = self
+            .table
+            .get(&self_)
+            .map_err(|e| Error::Other(format!("Failed to get store resource: {}", e)))?;
+
+        store
+            .state_store
+            .merge(key.key_group, key.key, key.namespace, key.user_key, value)
+            .map_err(|e| Error::Other(format!("Failed to merge: {}", e)))
     }
 
     fn delete_prefix(
@@ -422,6 +432,23 @@ impl functionstream::core::collector::Host for HostState {
         });
     }
 
+    fn emit_batch(&mut self, target_id: u32, records: Vec<Vec<u8>>) {
+        let output_count = self.outputs.len();
+        let out = self.outputs.get_mut(target_id as usize).unwrap_or_else(|| {
+            panic!("Invalid target_id: {target_id}, available outputs: {output_count}");
+        });
+
+        let channel = format!("target_{}", target_id);
+        for data in records {
+            let buffer_or_event =
+                BufferOrEvent::new_buffer(data, Some(channel.clone()), false, false);
+
+            out.collect(buffer_or_event).unwrap_or_else(|e| {
+                panic!("failed to collect output: {e}");
+            });
+        }
+    }
+
     fn emit_watermark(&mut self, target_id: u32, ts: u64) {
         let output_count = self.outputs.len();
         let out = self.outputs.get_mut(target_id as usize).unwrap_or_else(|| {
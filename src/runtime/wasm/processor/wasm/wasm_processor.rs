@@ -15,6 +15,7 @@
 // This module provides a concrete implementation of the WasmProcessor trait
 // that can load and execute WebAssembly modules.
 
+use super::wasm_host::functionstream::core::types::ErrorInfo;
 use super::wasm_host::{HostState, Processor};
 use super::wasm_processor_trait::WasmProcessor;
 use crate::runtime::output::Output;
@@ -37,6 +38,16 @@ pub enum WasmProcessorError {
     ModuleNotFound(String),
     /// Invalid wasm module
     InvalidModule(String),
+    /// The guest driver returned an error from an export
+    DriverError(ErrorInfo),
+}
+
+impl WasmProcessorError {
+    /// Whether the guest marked the failure as transient (e.g. store IO), so
+    /// repeating the same call may succeed.
+    pub fn is_retryable(&self) -> bool {
+        matches!(self, WasmProcessorError::DriverError(info) if info.retryable)
+    }
 }
 
 impl fmt::Display for WasmProcessorError {
@@ -53,12 +64,27 @@ impl fmt::Display for WasmProcessorError {
                 write!(f, "wasm module not found: {}", path)
             }
             WasmProcessorError::InvalidModule(msg) => write!(f, "Invalid wasm module: {}", msg),
+            WasmProcessorError::DriverError(info) => {
+                let retryable = if info.retryable { " (retryable)" } else { "" };
+                write!(
+                    f,
+                    "wasm driver error {}{}: {}",
+                    info.code, retryable, info.message
+                )
+            }
         }
     }
 }
 
 impl Error for WasmProcessorError {}
 
+/// Converts the error-info returned by a processor export into a WasmProcessorError.
+fn driver_result<T>(result: Result<T, ErrorInfo>) -> Result<T, Box<dyn Error + Send>> {
+    result.map_err(|info| -> Box<dyn Error + Send> {
+        Box::new(WasmProcessorError::DriverError(info))
+    })
+}
+
 pub struct WasmProcessorImpl {
     modules: Vec<(String, Vec<u8>)>,
     name: String,
@@ -179,7 +205,7 @@ impl WasmProcessor for WasmProcessorImpl {
             ))
         })?;
 
-        processor
+        let result = processor
             .call_fs_process(&mut *store, input_index as u32, &data)
             .map_err(|e| -> Box<dyn Error + Send> {
                 Box::new(WasmProcessorError::ExecutionError(format!(
@@ -187,6 +213,53 @@ impl WasmProcessor for WasmProcessorImpl {
                     e
                 )))
             })?;
+        driver_result(result)?;
+
+        Ok(())
+    }
+
+    /// Process a batch of records from one input with a single fs-process-batch call
+    ///
+    /// # Arguments
+    /// * `records` - Input records in arrival order
+    /// * `input_index` - Index of the input source (0-based)
+    fn process_batch(
+        &self,
+        records: Vec<Vec<u8>>,
+        input_index: usize,
+    ) -> Result<(), Box<dyn Error + Send>> {
+        if !self.initialized {
+            return Err(Box::new(WasmProcessorError::InitError(
+                "Processor not initialized. Call init_with_context() first.".to_string(),
+            )));
+        }
+
+        let processor_ref = self.processor.borrow();
+        let processor = processor_ref
+            .as_ref()
+            .ok_or_else(|| -> Box<dyn Error + Send> {
+                Box::new(WasmProcessorError::InitError(
+                    "WasmHost not initialized. Call init_wasm_host() first.".to_string(),
+                ))
+            })?;
+
+        let mut store_ref = self.store.borrow_mut();
+        let store = store_ref.as_mut().ok_or_else(|| -> Box<dyn Error + Send> {
+            Box::new(WasmProcessorError::InitError(
+                "WasmHost not initialized. Call init_wasm_host() first.".to_string(),
+            ))
+        })?;
+
+        // WIT: export fs-process-batch: func(source-id: u32, records: list<list<u8>>) -> result<_, error-info>;
+        let result = processor
+            .call_fs_process_batch(&mut *store, input_index as u32, &records)
+            .map_err(|e| -> Box<dyn Error + Send> {
+                Box::new(WasmProcessorError::ExecutionError(format!(
+                    "Failed to call wasm process_batch: {}",
+                    e
+                )))
+            })?;
+        driver_result(result)?;
 
         Ok(())
     }
@@ -225,8 +298,8 @@ impl WasmProcessor for WasmProcessorImpl {
         })?;
 
         // Call wasm process_watermark function
-        // WIT: export fs-process-watermark: func(source-id: u32, watermark: u64);
-        processor
+        // WIT: export fs-process-watermark: func(source-id: u32, watermark: u64) -> result<_, error-info>;
+        let result = processor
             .call_fs_process_watermark(store, input_index as u32, timestamp)
             .map_err(|e| -> Box<dyn Error + Send> {
                 Box::new(WasmProcessorError::ExecutionError(format!(
@@ -234,6 +307,7 @@ impl WasmProcessor for WasmProcessorImpl {
                     e
                 )))
             })?;
+        driver_result(result)?;
 
         // Update current watermark
         #[allow(clippy::unnecessary_map_or)]
@@ -290,8 +364,8 @@ impl WasmProcessor for WasmProcessorImpl {
         })?;
 
         // Call wasm take_checkpoint function
-        // WIT: export fs-take-checkpoint: func(checkpoint-id: u64) -> list<u8>;
-        processor
+        // WIT: export fs-take-checkpoint: func(checkpoint-id: u64) -> result<_, error-info>;
+        let result = processor
             .call_fs_take_checkpoint(store, checkpoint_id)
             .map_err(|e| -> Box<dyn Error + Send> {
                 Box::new(WasmProcessorError::ExecutionError(format!(
@@ -299,6 +373,7 @@ impl WasmProcessor for WasmProcessorImpl {
                     e
                 )))
             })?;
+        driver_result(result)?;
 
         log::debug!(
             "WasmProcessor '{}' checkpoint {} created",
@@ -510,7 +585,7 @@ impl WasmProcessor for WasmProcessorImpl {
                     .cloned()
                     .unwrap_or_else(|| self.name.clone());
 
-                tokio::task::block_in_place(|| {
+                let result = tokio::task::block_in_place(|| {
                     processor
                         .call_fs_exec(store, &class_name, &self.modules)
                         .map_err(|e| -> Box<dyn Error + Send> {
@@ -520,11 +595,12 @@ impl WasmProcessor for WasmProcessorImpl {
                             )))
                         })
                 })?;
+                driver_result(result)?;
             }
 
             let mut store_ref = self.store.borrow_mut();
             let store = store_ref.as_mut().unwrap();
-            tokio::task::block_in_place(|| {
+            let result = tokio::task::block_in_place(|| {
                 processor
                     .call_fs_init(store, &config_list)
                     .map_err(|e| -> Box<dyn Error + Send> {
@@ -534,6 +610,7 @@ impl WasmProcessor for WasmProcessorImpl {
                         )))
                     })
             })?;
+            driver_result(result)?;
         }
 
         Ok(())
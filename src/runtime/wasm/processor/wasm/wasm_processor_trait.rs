@@ -20,6 +20,19 @@ pub trait WasmProcessor: Send + Sync {
         input_index: usize,
     ) -> Result<(), Box<dyn std::error::Error + Send>>;
 
+    /// Process consecutive records from one input in a single call. The
+    /// default forwards each record to `process`.
+    fn process_batch(
+        &self,
+        records: Vec<Vec<u8>>,
+        input_index: usize,
+    ) -> Result<(), Box<dyn std::error::Error + Send>> {
+        for data in records {
+            self.process(data, input_index)?;
+        }
+        Ok(())
+    }
+
     fn process_watermark(
         &mut self,
         timestamp: u64,
@@ -13,7 +13,6 @@
 use super::input_strategy::{InputStrategy, RoundRobinStrategy, from_selector_name};
 use super::thread_pool::ThreadGroup;
 use super::wasm_processor_trait::WasmProcessor;
-use crate::runtime::buffer_and_event::BufferOrEvent;
 use crate::runtime::common::{ComponentState, TaskCompletionFlag};
 use crate::runtime::input::Input;
 use crate::runtime::output::Output;
@@ -486,6 +485,10 @@ impl WasmTask {
         let mut batch_count = 0;
         let mut made_progress = false;
         let max_batch = processor_runtime.max_batch_size;
+        // Consecutive records from the same input cross the WASM boundary
+        // together through fs-process-batch.
+        let mut pending: Vec<Vec<u8>> = Vec::new();
+        let mut pending_input = 0;
 
         while batch_count < max_batch {
             let mask = strategy.next_mask(input_count, *last_idx, *finished_mask);
@@ -497,7 +500,14 @@ impl WasmTask {
 
             match inputs[i].get_next() {
                 Ok(Some(data)) => {
-                    Self::process_single_record(data, processor, inputs[i].get_group_id())?;
+                    let input_index = inputs[i].get_group_id();
+                    if let Some(buf) = data.get_buffer() {
+                        if !pending.is_empty() && pending_input != input_index {
+                            Self::flush_records(&mut pending, processor, pending_input)?;
+                        }
+                        pending_input = input_index;
+                        pending.push(buf.to_vec());
+                    }
                     *last_idx = i;
                     made_progress = true;
                     batch_count += 1;
@@ -509,19 +519,24 @@ impl WasmTask {
                 }
             }
         }
+        Self::flush_records(&mut pending, processor, pending_input)?;
         Ok(made_progress)
     }
 
     #[inline]
-    fn process_single_record(
-        data: BufferOrEvent,
+    fn flush_records(
+        pending: &mut Vec<Vec<u8>>,
         processor: &mut Box<dyn WasmProcessor>,
         input_index: usize,
     ) -> Result<(), FunctionErrorReport> {
-        if let Some(buf) = data.get_buffer() {
-            processor
-                .process(buf.to_vec(), input_index)
-                .map_err(|e| FunctionErrorReport::processor(input_index, e.to_string()))?;
+        match pending.len() {
+            0 => {}
+            1 => processor
+                .process(pending.pop().unwrap(), input_index)
+                .map_err(|e| FunctionErrorReport::processor(input_index, e.to_string()))?,
+            _ => processor
+                .process_batch(std::mem::take(pending), input_index)
+                .map_err(|e| FunctionErrorReport::processor(input_index, e.to_string()))?,
         }
         Ok(())
     }
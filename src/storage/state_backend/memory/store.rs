@@ -102,7 +102,6 @@ impl StateStore for MemoryStateStore {
 
         let merged = if let Some(existing_value) = existing {
             let mut result = existing_value;
-            result.push(b'\0');
             result.extend_from_slice(&value);
             result
         } else {
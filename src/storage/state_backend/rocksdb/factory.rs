@@ -175,13 +175,13 @@ fn merge_operator(
 
     let mut buf = Vec::new();
 
-    for operand in operands {
-        buf.write_all(operand).ok()?;
-    }
-
     if let Some(existing) = existing_val {
         buf.write_all(existing).ok()?;
     }
 
+    for operand in operands {
+        buf.write_all(operand).ok()?;
+    }
+
     Some(buf)
 }
@@ -162,7 +162,8 @@ pub trait StateStore: Send + Sync {
         self.delete_state(key_bytes)
     }
 
-    /// Merge value (complex key, using merge operation)
+    /// Merge value (complex key, using merge operation): the value's bytes are
+    /// appended to the stored value, or stored as-is when the key is absent
     ///
     /// # Arguments
     /// - `key_group`: key group (byte array)